@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+)
+
+// ---------------------------------------------------------
+// 压缩包后端相关代码
+// ---------------------------------------------------------
+
+// Archiver 是列出, 解压, 测试和创建压缩包的后端
+// 各方法返回 7zz 格式的原始输出, 由 parse7zzListSlt, parse7zzTest 等解析, 出错时 err 不为 nil
+type Archiver interface {
+	// List 返回 l -slt 的输出; unwrapTar 为 true 表示外层是压缩流, 返回的是其中 tar 的列表
+	List(ctx context.Context, archivePath string, password []byte, codePage int, onStall func(stalled bool)) (output string, unwrapTar bool, err error)
+	Extract(ctx context.Context, req extractRequest, onProgress func(progressInfo), onStall func(stalled bool)) (string, error)
+	Test(ctx context.Context, archivePath string, password []byte, codePage int, paths []string, onProgress func(progressInfo), onStall func(stalled bool)) (string, error)
+	Add(ctx context.Context, req createRequest, onProgress func(progressInfo), onStall func(stalled bool)) (string, error)
+	// ExtractFile 把单个条目解压到 dir, 返回解压后的文件路径, 用于打开嵌套的压缩包
+	ExtractFile(ctx context.Context, archivePath string, password []byte, codePage int, unwrapTar bool, name string, dir string, onProgress func(progressInfo), onStall func(stalled bool)) (dst string, output string, err error)
+	// Preview 读取单个条目的前 limit 字节, 读满时 truncated 为 true
+	Preview(ctx context.Context, archivePath string, password []byte, codePage int, unwrapTar bool, name string, limit uint64) (data []byte, truncated bool, output string, err error)
+}
+
+// archiver 为当前使用的后端, 默认调用 7zz 命令行, 测试时替换为回放录制输出的假后端
+var archiver Archiver = cliArchiver{}
+
+// cliArchiver 通过 sevenZipPath 指向的 7zz 命令行实现 Archiver
+type cliArchiver struct{}
+
+func (cliArchiver) List(ctx context.Context, archivePath string, password []byte, codePage int, onStall func(stalled bool)) (string, bool, error) {
+	return listArchive(ctx, archivePath, password, codePage, onStall)
+}
+
+func (cliArchiver) Extract(ctx context.Context, req extractRequest, onProgress func(progressInfo), onStall func(stalled bool)) (string, error) {
+	return run7zzExtract(ctx, req, onProgress, onStall)
+}
+
+func (cliArchiver) Test(ctx context.Context, archivePath string, password []byte, codePage int, paths []string, onProgress func(progressInfo), onStall func(stalled bool)) (string, error) {
+	return run7zzTest(ctx, archivePath, password, codePage, paths, onProgress, onStall)
+}
+
+func (cliArchiver) Add(ctx context.Context, req createRequest, onProgress func(progressInfo), onStall func(stalled bool)) (string, error) {
+	return run7zzAdd(ctx, req, onProgress, onStall)
+}
+
+func (cliArchiver) ExtractFile(ctx context.Context, archivePath string, password []byte, codePage int, unwrapTar bool, name string, dir string, onProgress func(progressInfo), onStall func(stalled bool)) (string, string, error) {
+	return run7zzExtractFile(ctx, archivePath, password, codePage, unwrapTar, name, dir, onProgress, onStall)
+}
+
+func (cliArchiver) Preview(ctx context.Context, archivePath string, password []byte, codePage int, unwrapTar bool, name string, limit uint64) ([]byte, bool, string, error) {
+	return run7zzPreview(ctx, archivePath, password, codePage, unwrapTar, name, limit)
+}
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 批量解压队列相关代码
+// ---------------------------------------------------------
+
+const prefKeyBatchConcurrency = "batchConcurrency"
+
+type jobStatus int
+
+const (
+	jobPending jobStatus = iota
+	jobListing
+	jobExtracting
+	jobDone
+	jobFailed
+)
+
+var jobStatusLabels = []string{"等待中", "读取列表", "解压中", "完成", "失败"}
+
+// batchJob 是队列中的一个压缩包
+type batchJob struct {
+	path          string
+	password      []byte // 归该任务所有, 失败时保留以便重试, 完成或关闭窗口时清零
+	status        jobStatus
+	percent       int
+	message       string // 成功时为解压目录, 失败时为原因
+	needsPassword bool
+	cancel        context.CancelFunc
+}
+
+func (j *batchJob) statusText() string {
+	switch j.status {
+	case jobExtracting:
+		return fmt.Sprintf("%s %d%%", jobStatusLabels[j.status], j.percent)
+	case jobFailed:
+		return jobStatusLabels[j.status] + ": " + j.message
+	}
+	return jobStatusLabels[j.status]
+}
+
+// batchQueue 管理批量解压任务, 所有字段只在 UI 线程中读写
+type batchQueue struct {
+	win        fyne.Window
+	jobs       []*batchJob
+	running    int
+	list       *widget.List
+	summaryLbl *widget.Label
+	ctx        context.Context
+	cancelAll  context.CancelFunc
+	closed     bool
+}
+
+// activeQueue 为当前打开的队列窗口, 再次拖入多个压缩包时追加到其中
+var activeQueue *batchQueue
+
+// isArchiveFile 根据扩展名判断文件是否为 7zz 可以打开的压缩包
+func isArchiveFile(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if hasArchiveExt(path) {
+		return true
+	}
+	// .r00, .z01, .002 这类分卷没有常见的压缩包扩展名
+	_, ok := detectVolumeSet(path)
+	return ok
+}
+
+// hasArchiveExt 只根据文件名判断是否为压缩包, 也用于压缩包内的条目
+func hasArchiveExt(name string) bool {
+	switch detectArchiveSuffix(name) {
+	case ".7z", ".zip", ".rar", ".tar", ".gz", ".tgz", ".bz2", ".tbz2", ".xz", ".txz",
+		".tar.gz", ".tar.bz2", ".tar.xz", ".tar.zst", ".tar.lz4", ".tar.lzma", ".tzst", ".zst", ".lz4", ".lzma", ".z", ".cab", ".arj",
+		".iso", ".wim", ".dmg", ".jar", ".apk", ".001":
+		return true
+	}
+	return false
+}
+
+func allArchives(paths []string) bool {
+	for _, p := range paths {
+		if !isArchiveFile(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func loadBatchConcurrency() int {
+	n := fyne.CurrentApp().Preferences().IntWithFallback(prefKeyBatchConcurrency, BATCH_CONCURRENCY_DEFAULT)
+	if n < 1 || n > BATCH_CONCURRENCY_MAX {
+		return BATCH_CONCURRENCY_DEFAULT
+	}
+	return n
+}
+
+// showBatchQueue 把压缩包加入批量队列, 队列窗口不存在时创建
+func showBatchQueue(paths []string) {
+	if activeQueue == nil {
+		activeQueue = newBatchQueue()
+	}
+	q := activeQueue
+	for _, p := range paths {
+		q.jobs = append(q.jobs, &batchJob{path: p})
+	}
+	q.list.Refresh()
+	q.win.Show()
+	q.win.RequestFocus()
+	q.pump()
+}
+
+func newBatchQueue() *batchQueue {
+	q := &batchQueue{}
+	q.ctx, q.cancelAll = context.WithCancel(context.Background())
+	q.win = fyne.CurrentApp().NewWindow("批量解压")
+	q.win.Resize(fyne.NewSize(WINDOW_WIDTH*0.8, WINDOW_HEIGHT*0.8))
+
+	q.list = widget.NewList(
+		func() int { return len(q.jobs) },
+		func() fyne.CanvasObject {
+			nameLbl := widget.NewLabel("")
+			nameLbl.Truncation = fyne.TextTruncateEllipsis
+			statusLbl := widget.NewLabel("")
+			retryBtn := widget.NewButton("重试", nil)
+			retryBtn.Importance = widget.LowImportance
+			return container.NewBorder(nil, nil, nil, container.NewHBox(statusLbl, retryBtn), nameLbl)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id < 0 || id >= len(q.jobs) {
+				return
+			}
+			job := q.jobs[id]
+			c := obj.(*fyne.Container)
+			right := c.Objects[1].(*fyne.Container)
+			c.Objects[0].(*widget.Label).SetText(filepath.Base(job.path))
+			right.Objects[0].(*widget.Label).SetText(job.statusText())
+			retryBtn := right.Objects[1].(*widget.Button)
+			retryBtn.OnTapped = func() { q.retry(job) }
+			if job.status == jobFailed {
+				retryBtn.Show()
+			} else {
+				retryBtn.Hide()
+			}
+		},
+	)
+
+	// SetSelected 会触发 pump, 需要先创建汇总标签
+	q.summaryLbl = widget.NewLabel("")
+	concurrencyLabels := make([]string, BATCH_CONCURRENCY_MAX)
+	for i := range concurrencyLabels {
+		concurrencyLabels[i] = strconv.Itoa(i + 1)
+	}
+	concurrencySel := widget.NewSelect(concurrencyLabels, func(s string) {
+		if n, err := strconv.Atoi(s); err == nil {
+			fyne.CurrentApp().Preferences().SetInt(prefKeyBatchConcurrency, n)
+			q.pump()
+		}
+	})
+	concurrencySel.SetSelected(strconv.Itoa(loadBatchConcurrency()))
+
+	retryAllBtn := widget.NewButton("重试全部失败项", func() {
+		for _, job := range q.jobs {
+			if job.status == jobFailed && !job.needsPassword {
+				job.status = jobPending
+				job.message = ""
+			}
+		}
+		q.list.Refresh()
+		q.pump()
+	})
+	clearBtn := widget.NewButton("清除已完成", func() {
+		kept := q.jobs[:0]
+		for _, job := range q.jobs {
+			if job.status != jobDone {
+				kept = append(kept, job)
+			}
+		}
+		q.jobs = kept
+		q.list.Refresh()
+		q.refreshSummary()
+	})
+	cancelBtn := widget.NewButton("全部取消", func() {
+		for _, job := range q.jobs {
+			if job.status == jobPending {
+				job.status = jobFailed
+				job.message = "已取消"
+			}
+			if job.cancel != nil {
+				job.cancel()
+			}
+		}
+		q.list.Refresh()
+	})
+
+	toolbar := container.NewHBox(widget.NewLabel("同时解压:"), concurrencySel, retryAllBtn, clearBtn, cancelBtn)
+	q.win.SetContent(container.NewBorder(toolbar, q.summaryLbl, nil, nil, q.list))
+
+	q.win.SetOnClosed(func() {
+		q.closed = true
+		q.cancelAll()
+		for _, job := range q.jobs {
+			clear(job.password)
+			job.password = nil
+		}
+		activeQueue = nil
+	})
+	return q
+}
+
+// pump 在并发数允许的范围内启动等待中的任务, 全部结束时显示汇总
+func (q *batchQueue) pump() {
+	limit := loadBatchConcurrency()
+	for _, job := range q.jobs {
+		if q.running >= limit {
+			break
+		}
+		if job.status == jobPending {
+			q.run(job)
+		}
+	}
+	q.refreshSummary()
+}
+
+func (q *batchQueue) refreshSummary() {
+	counts := make([]int, len(jobStatusLabels))
+	for _, job := range q.jobs {
+		counts[job.status]++
+	}
+	q.summaryLbl.SetText(fmt.Sprintf("共 %d 个, 完成 %d 个, 失败 %d 个, 进行中 %d 个, 等待 %d 个",
+		len(q.jobs), counts[jobDone], counts[jobFailed], counts[jobListing]+counts[jobExtracting], counts[jobPending]))
+}
+
+// retry 重新运行单个失败的任务, 需要密码时先询问
+func (q *batchQueue) retry(job *batchJob) {
+	if job.status != jobFailed {
+		return
+	}
+	restart := func() {
+		job.status = jobPending
+		job.message = ""
+		q.list.Refresh()
+		q.pump()
+	}
+	if job.needsPassword {
+		askPassword(q.ctx, q.win, job.path, 0, nil, func(pwd []byte) {
+			clear(job.password)
+			job.password = pwd
+			restart()
+		}, func() {})
+		return
+	}
+	restart()
+}
+
+// run 在后台依次列出并解压一个压缩包
+func (q *batchQueue) run(job *batchJob) {
+	ctx, cancel := context.WithCancel(q.ctx)
+	job.cancel = cancel
+	job.status = jobListing
+	job.percent = 0
+	job.needsPassword = false
+	q.running++
+	q.list.Refresh()
+
+	// 批量解压无法逐个询问冲突, 询问模式改为重命名新文件以免覆盖已有文件
+	overwrite := loadOverwriteMode()
+	if overwrite == overwriteAsk {
+		overwrite = overwriteRenameNew
+	}
+	layout := loadDefaultLayout()
+	limits := loadBombLimits()
+	if len(job.password) == 0 {
+		job.password = cachedPassword(job.path)
+	}
+	// 后台使用自己的副本, 关闭窗口时清零 job.password 不会影响正在运行的 7zz
+	password := clonePassword(job.password)
+
+	go func() {
+		defer cancel()
+
+		// end 在 UI 线程中清零副本并结束任务
+		end := func(status jobStatus, msg string) {
+			clear(password)
+			q.finish(job, status, msg)
+		}
+
+		// 批量任务无人值守, 7zz 卡住时直接终止并记为失败
+		var stalled atomic.Bool
+		onStall := cancelOnStall(cancel, &stalled)
+		// fail 把 7zz 的输出归类为失败原因, failMsg 直接使用界面自己给出的原因
+		fail := func(output string, err error) {
+			msg := batchFailureMessage(ctx, output, err)
+			if stalled.Load() {
+				msg = fmt.Sprintf("7zz 超过 %d 秒没有输出, 已终止", STALL_TIMEOUT_SECONDS)
+			}
+			fyne.Do(func() {
+				job.needsPassword = needsPassword(output, err)
+				end(jobFailed, msg)
+			})
+		}
+		failMsg := func(msg string) {
+			fyne.Do(func() { end(jobFailed, msg) })
+		}
+
+		output, unwrapTar, err := archiver.List(ctx, job.path, password, 0, onStall)
+		if err != nil {
+			fail(output, err)
+			return
+		}
+
+		items := parse7zzListSlt(output)
+		req := planOutput(extractRequest{
+			archivePath: job.path,
+			password:    password,
+			items:       items,
+			overwrite:   overwrite,
+			totalSize:   totalItemSize(items),
+			unwrapTar:   unwrapTar,
+		}, filepath.Dir(job.path), layout)
+		// 批量解压无人值守, 有风险的条目一律跳过
+		unsafe := findUnsafeEntries(req)
+		req.excludes = append(req.excludes, unsafeNames(unsafe)...)
+		// 超出限制时无法询问, 直接判为失败
+		if problems := checkBombLimits(req, limits); len(problems) > 0 {
+			failMsg("可能是解压炸弹: " + strings.Join(problems, "; "))
+			return
+		}
+		if shortage, short := checkFreeSpace(req); short {
+			failMsg("空间不足, 还差 " + formatSize(shortage.missing()))
+			return
+		}
+		if err := os.MkdirAll(req.outputDir, 0o755); err != nil {
+			failMsg("无法创建目录: " + err.Error())
+			return
+		}
+		if err := req.prepareStage(); err != nil {
+			failMsg("无法创建临时目录: " + err.Error())
+			return
+		}
+
+		fyne.Do(func() {
+			job.status = jobExtracting
+			q.list.Refresh()
+		})
+		watch := watchOutputSize(ctx, req, limits, cancel)
+		lastPercent := -1
+		output, err = archiver.Extract(ctx, req, func(info progressInfo) {
+			if info.percent == lastPercent {
+				return
+			}
+			lastPercent = info.percent
+			fyne.Do(func() {
+				job.percent = info.percent
+				q.list.Refresh()
+			})
+		}, onStall)
+		if stageErr := req.finishStage(); stageErr != nil && err == nil {
+			failMsg("无法移动到 " + req.smartTarget + ": " + stageErr.Error())
+			return
+		}
+		if watch.exceeded.Load() {
+			failMsg("写入超过声明的大小, 可能是解压炸弹, 已中止")
+			return
+		}
+		if warnings := parse7zzExtractWarnings(output, req); !needsPassword(output, err) && isPartialSuccess(err, warnings) {
+			result := fmt.Sprintf("%s (有 %d 个警告)", req.resultDir(), len(warnings))
+			if len(warnings) == 0 {
+				result = req.resultDir() + " (有警告)"
+				if tail := outputTail(output, 1); len(tail) > 0 {
+					result = req.resultDir() + " (有警告: " + tail[0] + ")"
+				}
+			}
+			fyne.Do(func() { end(jobDone, result) })
+			return
+		}
+		if err != nil {
+			fail(output, err)
+			return
+		}
+
+		result := req.resultDir()
+		if len(unsafe) > 0 {
+			result += fmt.Sprintf(" (跳过 %d 个有风险的条目)", len(unsafe))
+		}
+		fyne.Do(func() {
+			rememberPassword(job.path, password)
+			end(jobDone, result)
+		})
+	}()
+}
+
+func (q *batchQueue) finish(job *batchJob, status jobStatus, message string) {
+	if status == jobDone {
+		clear(job.password)
+		job.password = nil
+	}
+	job.status = status
+	job.message = message
+	job.cancel = nil
+	q.running--
+	if q.closed {
+		return
+	}
+	q.list.Refresh()
+	q.pump()
+
+	if q.running == 0 && !q.hasPending() {
+		q.showReport()
+	}
+}
+
+func (q *batchQueue) hasPending() bool {
+	for _, job := range q.jobs {
+		if job.status == jobPending {
+			return true
+		}
+	}
+	return false
+}
+
+// showReport 所有任务结束后显示汇总, 列出失败的压缩包及原因
+func (q *batchQueue) showReport() {
+	var done int
+	var failed []string
+	for _, job := range q.jobs {
+		switch job.status {
+		case jobDone:
+			done++
+		case jobFailed:
+			failed = append(failed, filepath.Base(job.path)+": "+job.message)
+		}
+	}
+
+	msg := fmt.Sprintf("批量解压结束: 成功 %d 个, 失败 %d 个", done, len(failed))
+	if len(failed) > 0 {
+		msg += "\n\n" + strings.Join(failed, "\n") + "\n\n可以在列表中重试失败的压缩包"
+	}
+	msgLabel := widget.NewLabel(msg)
+	msgLabel.Wrapping = fyne.TextWrapWord
+	dialog.ShowCustom("批量解压完成", "确定", container.NewVScroll(wrapWithMinSize(msgLabel)), q.win)
+}
+
+// batchFailureMessage 把 7zz 的输出概括为一行失败原因
+func batchFailureMessage(ctx context.Context, output string, err error) string {
+	switch {
+	case ctx.Err() != nil:
+		return "已取消"
+	case err != nil && is7zzNotFound(err):
+		return "找不到 7zz"
+	case needsPassword(output, err):
+		return "需要密码"
+	}
+	if e := classify7zzError(output, err); e != nil && e.kind != errUnknown {
+		return e.Error()
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return line
+		}
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return "未知错误"
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 解压炸弹 (压缩比异常的压缩包) 防护相关代码
+// ---------------------------------------------------------
+
+const (
+	prefKeyBombMaxSizeGB  = "bombMaxSizeGB"
+	prefKeyBombMaxRatio   = "bombMaxRatio"
+	prefKeyBombMaxEntries = "bombMaxEntries"
+	prefKeyBombBlock      = "bombBlock"
+)
+
+// bombLimits 是解压前检查的上限, 为 0 的项不检查; block 为 true 时超出直接阻止, 否则询问
+type bombLimits struct {
+	maxSizeGB  int
+	maxRatio   int
+	maxEntries int
+	block      bool
+}
+
+func loadBombLimits() bombLimits {
+	prefs := fyne.CurrentApp().Preferences()
+	return bombLimits{
+		maxSizeGB:  prefs.IntWithFallback(prefKeyBombMaxSizeGB, BOMB_MAX_SIZE_GB_DEFAULT),
+		maxRatio:   prefs.IntWithFallback(prefKeyBombMaxRatio, BOMB_MAX_RATIO_DEFAULT),
+		maxEntries: prefs.IntWithFallback(prefKeyBombMaxEntries, BOMB_MAX_ENTRIES_DEFAULT),
+		block:      prefs.Bool(prefKeyBombBlock),
+	}
+}
+
+func saveBombLimits(l bombLimits) {
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetInt(prefKeyBombMaxSizeGB, l.maxSizeGB)
+	prefs.SetInt(prefKeyBombMaxRatio, l.maxRatio)
+	prefs.SetInt(prefKeyBombMaxEntries, l.maxEntries)
+	prefs.SetBool(prefKeyBombBlock, l.block)
+}
+
+// selectedItems 返回将被解压的文件, 不包含文件夹和跳过的条目
+func selectedItems(req extractRequest) []archiveItem {
+	var out []archiveItem
+	for _, it := range req.items {
+		if it.isDir || !isUnderPaths(it.name, req.paths) || (len(req.excludes) > 0 && isUnderPaths(it.name, req.excludes)) {
+			continue
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
+// packedSize 返回选中内容的压缩后大小
+// 压缩的 tar 包列出的是 tar 内的条目, 没有压缩后大小, 改用压缩包文件本身的大小
+func packedSize(req extractRequest, items []archiveItem) uint64 {
+	var packed uint64
+	if !req.unwrapTar {
+		for _, it := range items {
+			packed += it.packed
+		}
+	}
+	if packed == 0 {
+		if info, err := os.Stat(req.archivePath); err == nil {
+			packed = uint64(info.Size())
+		}
+	}
+	return packed
+}
+
+// checkBombLimits 返回选中内容超出的限制, 没有超出时返回 nil
+func checkBombLimits(req extractRequest, limits bombLimits) []string {
+	items := selectedItems(req)
+	var size uint64
+	for _, it := range items {
+		size += it.size
+	}
+
+	var out []string
+	if limits.maxSizeGB > 0 && size > uint64(limits.maxSizeGB)<<30 {
+		out = append(out, fmt.Sprintf("解压后总大小 %s, 超过 %d GB", formatSize(size), limits.maxSizeGB))
+	}
+	if packed := packedSize(req, items); limits.maxRatio > 0 && packed > 0 {
+		// 用商和余数比较, 以免 1000.5:1 这样的压缩比取整后不算超过 1000:1
+		ratio, rem := size/packed, size%packed
+		if ratio > uint64(limits.maxRatio) || (ratio == uint64(limits.maxRatio) && rem > 0) {
+			out = append(out, fmt.Sprintf("压缩比 %.1f:1 (%s 解压后为 %s), 超过 %d:1", float64(size)/float64(packed), formatSize(packed), formatSize(size), limits.maxRatio))
+		}
+	}
+	if limits.maxEntries > 0 && len(items) > limits.maxEntries {
+		out = append(out, fmt.Sprintf("文件数量 %d, 超过 %d", len(items), limits.maxEntries))
+	}
+	return out
+}
+
+// showBombWarning 提示超出的限制, 阻止模式下只能取消
+func showBombWarning(win fyne.Window, problems []string, block bool, onContinue func(), onCancel func()) {
+	msg := "该压缩包可能是解压炸弹:\n\n" + strings.Join(problems, "\n")
+	if block {
+		msg += "\n\n已按设置阻止解压, 可在 `限制` 中调整上限."
+	} else {
+		msg += "\n\n是否仍然解压?"
+	}
+	msgLabel := widget.NewLabel(msg)
+	msgLabel.Wrapping = fyne.TextWrapWord
+	msgLabel.Alignment = fyne.TextAlignCenter
+
+	if block {
+		d := dialog.NewCustom("已阻止解压", "确定", wrapWithMinSize(msgLabel), win)
+		d.SetOnClosed(onCancel)
+		d.Show()
+		return
+	}
+	dialog.ShowCustomConfirm("解压炸弹警告", "仍然解压", "取消", wrapWithMinSize(msgLabel), func(ok bool) {
+		if ok {
+			onContinue()
+		} else {
+			onCancel()
+		}
+	}, win)
+}
+
+// showBombLimitsDialog 编辑解压前检查的上限
+func showBombLimitsDialog(win fyne.Window) {
+	limits := loadBombLimits()
+	intEntry := func(v int) *widget.Entry {
+		e := widget.NewEntry()
+		e.SetText(strconv.Itoa(v))
+		e.Validator = func(s string) error {
+			if n, err := strconv.Atoi(strings.TrimSpace(s)); err != nil || n < 0 {
+				return fmt.Errorf("请输入非负整数")
+			}
+			return nil
+		}
+		return e
+	}
+	sizeEntry := intEntry(limits.maxSizeGB)
+	ratioEntry := intEntry(limits.maxRatio)
+	entriesEntry := intEntry(limits.maxEntries)
+	blockCheck := widget.NewCheck("超出时直接阻止 (不勾选时询问是否继续)", nil)
+	blockCheck.SetChecked(limits.block)
+
+	hint := widget.NewLabel("填 0 表示不检查该项. 解压过程中写入的数据超过声明的大小时总会中止, 压缩包没有声明大小时按压缩比上限估算.")
+	hint.Wrapping = fyne.TextWrapWord
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("最大解压后大小 (GB)", sizeEntry),
+		widget.NewFormItem("最大压缩比", ratioEntry),
+		widget.NewFormItem("最大文件数量", entriesEntry),
+		widget.NewFormItem("", blockCheck),
+		widget.NewFormItem("", hint),
+	}
+
+	d := dialog.NewForm("解压限制", "保存", "取消", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		atoi := func(e *widget.Entry) int {
+			n, _ := strconv.Atoi(strings.TrimSpace(e.Text))
+			return n
+		}
+		saveBombLimits(bombLimits{
+			maxSizeGB:  atoi(sizeEntry),
+			maxRatio:   atoi(ratioEntry),
+			maxEntries: atoi(entriesEntry),
+			block:      blockCheck.Checked,
+		})
+	}, win)
+	d.Resize(fyne.NewSize(DIALOG_MIN_WIDTH, 0))
+	d.Show()
+}
+
+// outputWatch 在解压过程中定期统计本次解压写入的字节数, 超过声明的大小时取消解压
+type outputWatch struct {
+	exceeded  atomic.Bool
+	written   atomic.Uint64
+	limit     uint64
+	estimated bool // 压缩包没有声明解压后的大小, limit 按压缩比上限估算
+}
+
+// dirSize 返回目录下所有普通文件的大小之和, 不跟随链接; dir 为普通文件时返回它的大小
+func dirSize(dir string) uint64 {
+	var total uint64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += uint64(info.Size())
+		}
+		return nil
+	})
+	return total
+}
+
+// outputRoots 返回本次解压会写入的路径: 使用临时目录时为临时目录本身, 否则为选中条目在目标目录下的顶层文件或文件夹
+// 直接解压和智能解压的目标目录是压缩包所在的文件夹, 只统计这些路径, 既不会遍历整个文件夹,
+// 也不会把批量解压中同时写入该文件夹的其他压缩包算进来
+func outputRoots(req extractRequest) []string {
+	if req.stageDir != "" {
+		return []string{req.stageDir}
+	}
+	seen := make(map[string]bool)
+	var roots []string
+	for _, it := range selectedItems(req) {
+		parts := splitArchivePath(it.name)
+		if len(parts) == 0 {
+			continue
+		}
+		p := filepath.Join(req.extractDir(), parts[0])
+		if !seen[p] {
+			seen[p] = true
+			roots = append(roots, p)
+		}
+	}
+	return roots
+}
+
+func rootsSize(roots []string) uint64 {
+	var total uint64
+	for _, r := range roots {
+		total += dirSize(r)
+	}
+	return total
+}
+
+// watchOutputSize 在后台监视本次解压写入的路径, ctx 结束时停止; 实际写入超过声明的大小加上容差时调用 cancel
+// 单个 bz2, zstd, lz4 等压缩流不记录解压后的大小, 此时按压缩比上限估算, 没有设置压缩比上限时不监视
+func watchOutputSize(ctx context.Context, req extractRequest, limits bombLimits, cancel context.CancelFunc) *outputWatch {
+	items := selectedItems(req)
+	declared := req.totalSize
+	unknown := declared == 0
+	for _, it := range items {
+		if it.size == 0 && it.packed > 0 {
+			unknown = true
+		}
+	}
+	w := &outputWatch{}
+	if unknown {
+		if limits.maxRatio <= 0 {
+			return w
+		}
+		declared = packedSize(req, items) * uint64(limits.maxRatio)
+		w.estimated = true
+	}
+	w.limit = declared + declared/100 + BOMB_WATCH_SLACK_BYTES
+
+	roots := outputRoots(req)
+	go func() {
+		// 要写入的路径可能已经存在, 只统计新增的部分
+		base := rootsSize(roots)
+		ticker := time.NewTicker(BOMB_WATCH_INTERVAL_MS * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			size := rootsSize(roots)
+			if size <= base {
+				continue
+			}
+			w.written.Store(size - base)
+			if size-base > w.limit {
+				w.exceeded.Store(true)
+				cancel()
+				return
+			}
+		}
+	}()
+	return w
+}
+
+// message 返回因写入超量而中止时的说明
+func (w *outputWatch) message(dir string) string {
+	what := "压缩包声明的大小"
+	if w.estimated {
+		what = "按压缩比上限估算的大小"
+	}
+	return fmt.Sprintf("已写入 %s, 超过%s, 可能是解压炸弹, 解压已中止.\n已解压的文件保留在:\n%s",
+		formatSize(w.written.Load()), what, dir)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckBombLimits(t *testing.T) {
+	dir := t.TempDir()
+	tarball := filepath.Join(dir, "a.tar.gz")
+	if err := os.WriteFile(tarball, make([]byte, 10), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file := func(name string, size, packed uint64) archiveItem {
+		return archiveItem{name: name, size: size, packed: packed}
+	}
+	ratioOnly := bombLimits{maxRatio: 1000}
+
+	cases := []struct {
+		name   string
+		req    extractRequest
+		limits bombLimits
+		want   []string // 每个超出的限制中应包含的文字
+	}{
+		{"压缩比正好等于上限", extractRequest{items: []archiveItem{file("a", 1000*1000, 1000)}}, ratioOnly, nil},
+		{"压缩比超过上限不足 1", extractRequest{items: []archiveItem{file("a", 1000*1000+1, 1000)}}, ratioOnly, []string{"压缩比 1000.0:1"}},
+		{"压缩比超过上限", extractRequest{items: []archiveItem{file("a", 1001*1000, 1000)}}, ratioOnly, []string{"压缩比 1001.0:1"}},
+		{"压缩比按所有选中文件合计", extractRequest{items: []archiveItem{file("a", 600*1000, 1000), file("b", 600*1000, 100)}}, ratioOnly, []string{"压缩比"}},
+		{"上限为 0 时不检查压缩比", extractRequest{items: []archiveItem{file("a", 1<<40, 1)}}, bombLimits{}, nil},
+		{"没有压缩后大小也找不到文件时不检查压缩比", extractRequest{archivePath: filepath.Join(dir, "missing.7z"), items: []archiveItem{file("a", 1<<40, 0)}}, ratioOnly, nil},
+		{"tar 包改用压缩包文件的大小", extractRequest{archivePath: tarball, unwrapTar: true, items: []archiveItem{file("a", 10*1000, 5)}}, ratioOnly, nil},
+		{"tar 包超过上限", extractRequest{archivePath: tarball, unwrapTar: true, items: []archiveItem{file("a", 10*1000+1, 5)}}, ratioOnly, []string{"压缩比"}},
+		{"跳过的条目不计入", extractRequest{items: []archiveItem{file("a", 1000, 1000), file("bomb", 1<<40, 1)}, excludes: []string{"bomb"}}, ratioOnly, nil},
+		{"未选中的条目不计入", extractRequest{items: []archiveItem{file("a/x", 1000, 1000), file("b/bomb", 1<<40, 1)}, paths: []string{"a"}}, ratioOnly, nil},
+		{"总大小正好等于上限", extractRequest{items: []archiveItem{file("a", 1<<30, 1<<30)}}, bombLimits{maxSizeGB: 1}, nil},
+		{"总大小超过上限", extractRequest{items: []archiveItem{file("a", 1<<30+1, 1<<30)}}, bombLimits{maxSizeGB: 1}, []string{"超过 1 GB"}},
+		{"文件数量正好等于上限", extractRequest{items: []archiveItem{{name: "d", isDir: true}, file("d/a", 1, 1), file("d/b", 1, 1)}}, bombLimits{maxEntries: 2}, nil},
+		{"文件数量超过上限", extractRequest{items: []archiveItem{file("a", 1, 1), file("b", 1, 1), file("c", 1, 1)}}, bombLimits{maxEntries: 2}, []string{"文件数量 3"}},
+		{"同时超出多项", extractRequest{items: []archiveItem{file("a", 2<<30, 1), file("b", 1, 1)}}, bombLimits{maxSizeGB: 1, maxRatio: 1000, maxEntries: 1}, []string{"GB", "压缩比", "文件数量"}},
+	}
+	for _, c := range cases {
+		got := checkBombLimits(c.req, c.limits)
+		if len(got) != len(c.want) {
+			t.Errorf("%s: checkBombLimits = %q, want %d 项", c.name, got, len(c.want))
+			continue
+		}
+		for i, w := range c.want {
+			if !strings.Contains(got[i], w) {
+				t.Errorf("%s: 第 %d 项 = %q, want 包含 %q", c.name, i+1, got[i], w)
+			}
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// ---------------------------------------------------------
+// 文件名编码 (代码页) 相关代码
+// ---------------------------------------------------------
+
+// codePages 是可选的文件名编码, cp 为 0 表示不传 -mcp, 由 7zz 自行决定
+var codePages = []struct {
+	label string
+	cp    int
+}{
+	{"自动", 0},
+	{"UTF-8", 65001},
+	{"简体中文 GBK (936)", 936},
+	{"繁体中文 Big5 (950)", 950},
+	{"日文 Shift-JIS (932)", 932},
+	{"韩文 EUC-KR (949)", 949},
+	{"西欧 (1252)", 1252},
+	{"DOS 西欧 (850)", 850},
+	{"DOS 美国 (437)", 437},
+}
+
+// rawCodePage 为 ISO-8859-1, 每个字节对应一个字符, 用它列出的名称可以还原原始字节
+const rawCodePage = 28591
+
+// codePageSel 为主窗口中的文件名编码下拉框
+var codePageSel *widget.Select
+
+// codePageArgs 返回 7zz 的代码页参数, 只对没有 UTF-8 标志的文件名生效
+func codePageArgs(cp int) []string {
+	if cp == 0 {
+		return nil
+	}
+	return []string{"-mcp=" + strconv.Itoa(cp)}
+}
+
+func codePageLabel(cp int) string {
+	for _, c := range codePages {
+		if c.cp == cp {
+			return c.label
+		}
+	}
+	return strconv.Itoa(cp)
+}
+
+// newCodePageSelect 创建文件名编码下拉框, 用户选择了不同的编码时调用 onChanged
+func newCodePageSelect(onChanged func(cp int)) *widget.Select {
+	labels := make([]string, len(codePages))
+	for i, c := range codePages {
+		labels[i] = c.label
+	}
+	codePageSel = widget.NewSelect(labels, func(label string) {
+		for _, c := range codePages {
+			if c.label == label && c.cp != currentCodePage {
+				onChanged(c.cp)
+			}
+		}
+	})
+	codePageSel.SetSelected(codePageLabel(currentCodePage))
+	return codePageSel
+}
+
+// setCodePage 更新当前编码并同步下拉框, 不会触发重新读取列表
+func setCodePage(cp int) {
+	currentCodePage = cp
+	if codePageSel != nil {
+		codePageSel.SetSelected(codePageLabel(cp))
+	}
+}
+
+// codePageCandidates 是自动检测时尝试的编码, score 为单个字符的得分, 越像该语言的常用文字得分越高
+var codePageCandidates = []struct {
+	cp    int
+	enc   encoding.Encoding
+	score func(r rune) int
+}{
+	{936, simplifiedchinese.GBK, func(r rune) int {
+		if unicode.Is(unicode.Han, r) {
+			return 2
+		}
+		return 0
+	}},
+	{950, traditionalchinese.Big5, func(r rune) int {
+		if unicode.Is(unicode.Han, r) {
+			return 2
+		}
+		return 0
+	}},
+	{932, japanese.ShiftJIS, func(r rune) int {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana) && (r < 0xFF61 || r > 0xFF9F):
+			return 3
+		case unicode.Is(unicode.Han, r):
+			return 1
+		case r >= 0xFF61 && r <= 0xFF9F:
+			// 半角片假名很少出现在文件名中, 多半是其他编码被误读
+			return -3
+		}
+		return 0
+	}},
+	{949, korean.EUCKR, func(r rune) int {
+		if unicode.Is(unicode.Hangul, r) {
+			return 3
+		}
+		return 0
+	}},
+}
+
+// rawNameBytes 把以 rawCodePage 列出的名称还原为原始字节
+// 带 UTF-8 标志的名称不受代码页影响, 会包含大于 0xFF 的字符, 此时返回 false
+func rawNameBytes(name string) ([]byte, bool) {
+	b := make([]byte, 0, len(name))
+	nonASCII := false
+	for _, r := range name {
+		if r > 0xFF {
+			return nil, false
+		}
+		if r >= 0x80 {
+			nonASCII = true
+		}
+		b = append(b, byte(r))
+	}
+	return b, nonASCII
+}
+
+// detectCodePage 根据原始文件名猜测最可能的编码, 无法判断时返回 0
+func detectCodePage(rawNames []string) int {
+	var raws [][]byte
+	for _, name := range rawNames {
+		if b, ok := rawNameBytes(name); ok {
+			raws = append(raws, b)
+		}
+	}
+	if len(raws) == 0 {
+		return 0
+	}
+
+	allUTF8 := true
+	for _, b := range raws {
+		if !utf8.Valid(b) {
+			allUTF8 = false
+			break
+		}
+	}
+	if allUTF8 {
+		return 65001
+	}
+
+	best, bestScore := 0, 0
+	for _, c := range codePageCandidates {
+		score := 0
+		dec := c.enc.NewDecoder()
+		for _, b := range raws {
+			s, err := dec.Bytes(b)
+			if err != nil || strings.ContainsRune(string(s), utf8.RuneError) {
+				score = -1
+				break
+			}
+			for _, r := range string(s) {
+				if r >= 0x80 {
+					score += c.score(r)
+				}
+			}
+		}
+		if score > bestScore {
+			best, bestScore = c.cp, score
+		}
+	}
+	return best
+}
+
+// hasNonASCIIName 判断列表中是否有非 ASCII 的名称, 纯 ASCII 的压缩包不需要检测编码
+func hasNonASCIIName(items []archiveItem) bool {
+	for _, it := range items {
+		for i := 0; i < len(it.name); i++ {
+			if it.name[i] >= 0x80 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// suggestCodePage 在后台检测 zip 文件名的编码, 与当前显示不同时询问是否用该编码重新读取列表
+// 后台使用 password 的副本, 检测结束后清零
+func suggestCodePage(win fyne.Window, token uint64, archivePath string, password []byte, listOutput string, items []archiveItem) {
+	if !strings.EqualFold(parse7zzArchiveType(listOutput), "zip") || !hasNonASCIIName(items) {
+		return
+	}
+	// 在 UI 线程绑定当前会话, 后台 goroutine 不读取全局变量
+	ctx, cancel := context.WithCancel(sessionCtx)
+	password = clonePassword(password)
+	go func() {
+		defer cancel()
+		defer clear(password)
+		output, _, err := archiver.List(ctx, archivePath, password, rawCodePage, cancelOnStall(cancel, nil))
+		if err != nil {
+			return
+		}
+		raw := parse7zzListSlt(output)
+		cp := detectCodePage(itemNames(raw))
+		if cp == 0 || !namesChangeWith(raw, items, cp) {
+			return
+		}
+
+		fyne.Do(func() {
+			if token != dropCounter.Load() || archivePath != currentFile || currentCodePage != 0 {
+				return
+			}
+			msg := "部分文件名可能使用 " + codePageLabel(cp) + " 编码, 当前显示可能是乱码.\n是否使用该编码重新读取列表?"
+			msgLabel := widget.NewLabel(msg)
+			msgLabel.Wrapping = fyne.TextWrapWord
+			msgLabel.Alignment = fyne.TextAlignCenter
+			dialog.ShowCustomConfirm("文件名编码", "重新读取", "保持不变", wrapWithMinSize(msgLabel), func(ok bool) {
+				if ok && token == dropCounter.Load() {
+					// 通过下拉框切换, 与手动选择走同一条路径
+					codePageSel.SetSelected(codePageLabel(cp))
+				}
+			}, win)
+		})
+	}()
+}
+
+func itemNames(items []archiveItem) []string {
+	names := make([]string, len(items))
+	for i, it := range items {
+		names[i] = it.name
+	}
+	return names
+}
+
+// namesChangeWith 判断按 cp 解码后的名称是否与当前显示的名称不同
+func namesChangeWith(raw []archiveItem, shown []archiveItem, cp int) bool {
+	var enc encoding.Encoding
+	for _, c := range codePageCandidates {
+		if c.cp == cp {
+			enc = c.enc
+		}
+	}
+	shownNames := make(map[string]bool, len(shown))
+	for _, it := range shown {
+		shownNames[it.name] = true
+	}
+	for _, it := range raw {
+		b, ok := rawNameBytes(it.name)
+		if !ok {
+			continue
+		}
+		name := string(b)
+		if enc != nil {
+			s, err := enc.NewDecoder().Bytes(b)
+			if err != nil {
+				continue
+			}
+			name = string(s)
+		}
+		if !shownNames[name] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// rawListed 返回以 enc 编码的 name 用 rawCodePage 列出时的样子, 即每个字节对应一个字符
+func rawListed(t *testing.T, enc encoding.Encoding, name string) string {
+	t.Helper()
+	b := []byte(name)
+	if enc != nil {
+		var err error
+		if b, err = enc.NewEncoder().Bytes(b); err != nil {
+			t.Fatalf("%q 无法编码: %v", name, err)
+		}
+	}
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return string(r)
+}
+
+func TestDetectCodePage(t *testing.T) {
+	cases := []struct {
+		name  string
+		enc   encoding.Encoding
+		names []string
+		want  int
+	}{
+		{"GBK", simplifiedchinese.GBK, []string{"资料/说明文档.txt", "资料/图片.png"}, 936},
+		{"Big5", traditionalchinese.Big5, []string{"資料/說明檔案.txt"}, 950},
+		{"Shift_JIS", japanese.ShiftJIS, []string{"ドキュメント/てすと.txt"}, 932},
+		{"EUC-KR", korean.EUCKR, []string{"문서/한국어.txt"}, 949},
+		{"UTF-8 但没有标志", nil, []string{"资料/说明.txt", "docs/readme.txt"}, 65001},
+		{"纯 ASCII", nil, []string{"docs/readme.txt"}, 0},
+	}
+	for _, c := range cases {
+		raw := make([]string, len(c.names))
+		for i, n := range c.names {
+			raw[i] = rawListed(t, c.enc, n)
+		}
+		if got := detectCodePage(raw); got != c.want {
+			t.Errorf("%s: detectCodePage = %d, want %d", c.name, got, c.want)
+		}
+	}
+
+	// 带 UTF-8 标志的名称不受代码页影响, 不参与检测
+	if got := detectCodePage([]string{"说明.txt", rawListed(t, simplifiedchinese.GBK, "说明.txt")}); got != 936 {
+		t.Errorf("混有带 UTF-8 标志的名称: detectCodePage = %d, want 936", got)
+	}
+	if got := detectCodePage([]string{"说明.txt"}); got != 0 {
+		t.Errorf("只有带 UTF-8 标志的名称: detectCodePage = %d, want 0", got)
+	}
+	// 任何候选编码都无法解码时无法判断
+	if got := detectCodePage([]string{"ÿÿÿ.txt"}); got != 0 {
+		t.Errorf("无法解码: detectCodePage = %d, want 0", got)
+	}
+}
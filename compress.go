@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 创建压缩包相关代码
+// ---------------------------------------------------------
+
+// archiveFormat 描述一种可创建的压缩格式
+type archiveFormat struct {
+	label   string
+	ext     string
+	typeArg string   // 传给 7zz -t 的类型
+	methods []string // 可选的压缩方法, 为空表示由格式决定
+	solid   bool     // 是否支持固实压缩
+	tar     bool     // 先打包为 tar 再用 typeArg 压缩
+}
+
+var archiveFormats = []archiveFormat{
+	{label: "7z", ext: ".7z", typeArg: "7z", methods: []string{"LZMA2", "LZMA", "PPMd", "BZip2"}, solid: true},
+	{label: "zip", ext: ".zip", typeArg: "zip", methods: []string{"Deflate", "Deflate64", "BZip2", "LZMA"}},
+	{label: "tar.gz", ext: ".tar.gz", typeArg: "gzip", tar: true},
+	{label: "tar.xz", ext: ".tar.xz", typeArg: "xz", tar: true},
+}
+
+var compressionLevels = []struct {
+	label string
+	level int
+}{
+	{"仅存储", 0},
+	{"最快", 1},
+	{"快速", 3},
+	{"标准", 5},
+	{"最大", 7},
+	{"极限", 9},
+}
+
+// createRequest 描述一次创建压缩包的操作
+type createRequest struct {
+	inputs  []string
+	output  string
+	format  archiveFormat
+	level   int
+	method  string
+	solid   bool
+	threads int // 0 表示由 7zz 自动决定
+}
+
+// commonArgs 返回压缩级别和线程数参数
+func (req createRequest) commonArgs() []string {
+	level := req.level
+	// gzip 与 xz 不支持仅存储
+	if req.format.tar && level == 0 {
+		level = 1
+	}
+	args := []string{"-mx=" + strconv.Itoa(level)}
+	if req.threads > 0 {
+		args = append(args, "-mmt="+strconv.Itoa(req.threads))
+	}
+	return args
+}
+
+// args 返回 7zz 参数, tar 格式时 producer 负责打包, consumer 负责压缩
+func (req createRequest) args() (producer []string, consumer []string) {
+	if req.format.tar {
+		producer = []string{"a", "-ttar", "-so", "-an", "-bsp2", "--"}
+		producer = append(producer, req.inputs...)
+
+		innerName := strings.TrimSuffix(filepath.Base(req.output), filepath.Ext(req.output))
+		consumer = []string{"a", "-t" + req.format.typeArg, "-si" + innerName, "-bso1"}
+		consumer = append(consumer, req.commonArgs()...)
+		consumer = append(consumer, req.output)
+		return producer, consumer
+	}
+
+	args := []string{"a", "-t" + req.format.typeArg, "-bsp1", "-bso1"}
+	args = append(args, req.commonArgs()...)
+	switch req.format.typeArg {
+	case "7z":
+		if req.method != "" {
+			args = append(args, "-m0="+req.method)
+		}
+		if req.solid {
+			args = append(args, "-ms=on")
+		} else {
+			args = append(args, "-ms=off")
+		}
+	case "zip":
+		if req.method != "" {
+			args = append(args, "-mm="+req.method)
+		}
+	}
+	args = append(args, req.output, "--")
+	args = append(args, req.inputs...)
+	return args, nil
+}
+
+// defaultArchiveName 根据拖入的内容给出默认的压缩包名称 (不含扩展名)
+func defaultArchiveName(inputs []string) string {
+	if len(inputs) == 1 {
+		name := filepath.Base(inputs[0])
+		if info, err := os.Stat(inputs[0]); err == nil && !info.IsDir() {
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+		if name != "" {
+			return name
+		}
+	}
+	name := filepath.Base(filepath.Dir(inputs[0]))
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		return "archive"
+	}
+	return name
+}
+
+// inputsSize 统计所有输入文件的总大小, 用于估算速度和剩余时间
+func inputsSize(inputs []string) uint64 {
+	var total uint64
+	for _, in := range inputs {
+		_ = filepath.WalkDir(in, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				total += uint64(info.Size())
+			}
+			return nil
+		})
+	}
+	return total
+}
+
+// showCreateArchiveDialog 显示创建压缩包的设置对话框
+func showCreateArchiveDialog(win fyne.Window, inputs []string) {
+	msg := fmt.Sprintf("将压缩 %d 个项目", len(inputs))
+	if len(inputs) == 1 {
+		msg = "将压缩: " + filepath.Base(inputs[0])
+	}
+	infoLbl := widget.NewLabel(msg)
+	infoLbl.Truncation = fyne.TextTruncateEllipsis
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(defaultArchiveName(inputs))
+
+	dirEntry := widget.NewEntry()
+	dirEntry.SetText(filepath.Dir(inputs[0]))
+	browseBtn := widget.NewButton("浏览...", func() {
+		fd := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, win)
+				return
+			}
+			if uri != nil {
+				dirEntry.SetText(uri.Path())
+			}
+		}, win)
+		if lister, err := storage.ListerForURI(storage.NewFileURI(dirEntry.Text)); err == nil {
+			fd.SetLocation(lister)
+		}
+		fd.Show()
+	})
+
+	formatLabels := make([]string, len(archiveFormats))
+	for i, f := range archiveFormats {
+		formatLabels[i] = f.label
+	}
+	levelLabels := make([]string, len(compressionLevels))
+	for i, l := range compressionLevels {
+		levelLabels[i] = l.label
+	}
+	threadLabels := []string{"自动"}
+	for i := 1; i <= runtime.NumCPU(); i++ {
+		threadLabels = append(threadLabels, strconv.Itoa(i))
+	}
+
+	formatSel := widget.NewSelect(formatLabels, nil)
+	levelSel := widget.NewSelect(levelLabels, nil)
+	levelSel.SetSelected("标准")
+	methodSel := widget.NewSelect(nil, nil)
+	solidCheck := widget.NewCheck("固实压缩", nil)
+	solidCheck.SetChecked(true)
+	threadSel := widget.NewSelect(threadLabels, nil)
+	threadSel.SetSelected("自动")
+
+	previewLbl := widget.NewLabel("")
+	previewLbl.Wrapping = fyne.TextWrapWord
+
+	selectedFormat := func() archiveFormat {
+		for _, f := range archiveFormats {
+			if f.label == formatSel.Selected {
+				return f
+			}
+		}
+		return archiveFormats[0]
+	}
+	outputPath := func() string {
+		return filepath.Join(filepath.Clean(dirEntry.Text), nameEntry.Text+selectedFormat().ext)
+	}
+	updatePreview := func() {
+		previewLbl.SetText("将创建: " + outputPath())
+	}
+
+	formatSel.OnChanged = func(string) {
+		f := selectedFormat()
+		methodSel.Options = f.methods
+		if len(f.methods) > 0 {
+			methodSel.SetSelected(f.methods[0])
+			methodSel.Enable()
+		} else {
+			methodSel.ClearSelected()
+			methodSel.Disable()
+		}
+		if f.solid {
+			solidCheck.Enable()
+		} else {
+			solidCheck.Disable()
+		}
+		updatePreview()
+	}
+	nameEntry.OnChanged = func(string) { updatePreview() }
+	dirEntry.OnChanged = func(string) { updatePreview() }
+	formatSel.SetSelected(archiveFormats[0].label)
+
+	form := widget.NewForm(
+		widget.NewFormItem("名称", nameEntry),
+		widget.NewFormItem("位置", container.NewBorder(nil, nil, nil, browseBtn, dirEntry)),
+		widget.NewFormItem("格式", formatSel),
+		widget.NewFormItem("压缩级别", levelSel),
+		widget.NewFormItem("压缩方法", methodSel),
+		widget.NewFormItem("", solidCheck),
+		widget.NewFormItem("线程数", threadSel),
+	)
+	content := container.NewVBox(infoLbl, form, previewLbl)
+	wrapped := container.NewGridWrap(fyne.NewSize(480, content.MinSize().Height+40), content)
+
+	dialog.ShowCustomConfirm("创建压缩包", "压缩", "取消", wrapWithMinSize(wrapped), func(ok bool) {
+		if !ok {
+			return
+		}
+		if strings.TrimSpace(nameEntry.Text) == "" {
+			dialog.ShowError(errors.New("请输入压缩包名称"), win)
+			return
+		}
+		if info, err := os.Stat(dirEntry.Text); err != nil || !info.IsDir() {
+			dialog.ShowError(errors.New("目标文件夹不存在: "+dirEntry.Text), win)
+			return
+		}
+
+		req := createRequest{
+			inputs: inputs,
+			output: outputPath(),
+			format: selectedFormat(),
+			method: methodSel.Selected,
+			solid:  solidCheck.Checked && selectedFormat().solid,
+		}
+		for _, l := range compressionLevels {
+			if l.label == levelSel.Selected {
+				req.level = l.level
+			}
+		}
+		if n, err := strconv.Atoi(threadSel.Selected); err == nil {
+			req.threads = n
+		}
+
+		// 7zz 对已存在的压缩包会做增量更新, 这里明确询问是否替换
+		if _, err := os.Stat(req.output); err == nil {
+			dialog.ShowConfirm("文件已存在", req.output+"\n已存在, 是否替换?", func(replace bool) {
+				if !replace {
+					return
+				}
+				if err := os.Remove(req.output); err != nil {
+					dialog.ShowError(fmt.Errorf("无法删除已有文件: %s", err.Error()), win)
+					return
+				}
+				startCreateArchive(win, req)
+			}, win)
+			return
+		}
+		startCreateArchive(win, req)
+	}, win)
+}
+
+// startCreateArchive 在进度对话框中运行 7zz a
+func startCreateArchive(win fyne.Window, req createRequest) {
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := newTaskProgress(nil)
+	progressContent := container.NewGridWrap(fyne.NewSize(480, progress.container.MinSize().Height), progress.container)
+	d := dialog.NewCustomWithoutButtons("正在压缩: "+filepath.Base(req.output), progressContent, win)
+	progress.start(0, cancel)
+	d.Show()
+
+	go func() {
+		defer cancel()
+
+		total := inputsSize(req.inputs)
+		fyne.Do(func() { progress.totalSize = total })
+
+		onStall := newStallPrompt(win, cancel, func() bool { return true })
+		output, err := archiver.Add(ctx, req, func(info progressInfo) {
+			fyne.Do(func() { progress.update(info) })
+		}, onStall)
+		canceled := ctx.Err() != nil
+
+		// 取消或失败时留下的压缩包不完整, 直接删除
+		if canceled || err != nil {
+			_ = os.Remove(req.output)
+		}
+
+		fyne.Do(func() {
+			progress.stop()
+			d.Hide()
+
+			switch {
+			case canceled:
+				dialog.ShowInformation("已取消", "已取消压缩, 未完成的压缩包已删除", win)
+			case err != nil && is7zzNotFound(err):
+				dialog.ShowError(fmt.Errorf("找不到 7zz.\n请把 7zz 文件和本程序放在同一个文件夹.\n当前尝试路径: %s", sevenZipPath), win)
+			case err != nil:
+				show7zzError(win, "压缩失败", output, err)
+			default:
+				msgLabel := widget.NewLabel("压缩包已创建:\n" + req.output)
+				msgLabel.Wrapping = fyne.TextWrapWord
+				msgLabel.Alignment = fyne.TextAlignCenter
+				dialog.ShowCustom("完成", "确定", wrapWithMinSize(msgLabel), win)
+			}
+		})
+	}()
+}
+
+// run7zzAdd 按 req 创建压缩包, tar 格式时通过管道先打包再压缩
+func run7zzAdd(ctx context.Context, req createRequest, onProgress func(progressInfo), onStall func(stalled bool)) (string, error) {
+	onLine := func(line string) {
+		if info, ok := parse7zzProgress(line); ok && onProgress != nil {
+			onProgress(info)
+		}
+	}
+	producer, consumer := req.args()
+	if consumer != nil {
+		return run7zzPipeline(ctx, onLine, onStall, nil, producer, consumer)
+	}
+	return run7zzStream(ctx, onLine, onStall, nil, producer...)
+}
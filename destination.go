@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 解压目标目录选择相关代码
+// ---------------------------------------------------------
+
+const prefKeyRecentDestinations = "recentDestinations"
+
+// recentDestinations 返回最近使用过的解压目录, 最近的在前, 已不存在的目录会被过滤
+func recentDestinations() []string {
+	list := fyne.CurrentApp().Preferences().StringList(prefKeyRecentDestinations)
+	out := make([]string, 0, len(list))
+	for _, dir := range list {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			out = append(out, dir)
+		}
+	}
+	return out
+}
+
+// addRecentDestination 把目录放到最近使用列表的最前面
+func addRecentDestination(dir string) {
+	list := []string{dir}
+	for _, d := range recentDestinations() {
+		if d != dir && len(list) < RECENT_DEST_MAX {
+			list = append(list, d)
+		}
+	}
+	fyne.CurrentApp().Preferences().SetStringList(prefKeyRecentDestinations, list)
+}
+
+// showExtractToDialog 让用户选择解压目录与布局, 确认后以规划好解压目录的 req 调用 onConfirm
+func showExtractToDialog(win fyne.Window, req extractRequest, onConfirm func(req extractRequest)) {
+	recent := recentDestinations()
+
+	dirEntry := widget.NewEntry()
+	dirEntry.PlaceHolder = "请选择目标文件夹"
+	if len(recent) > 0 {
+		dirEntry.SetText(recent[0])
+	} else {
+		dirEntry.SetText(req.sourceDir)
+	}
+
+	layoutRadio := widget.NewRadioGroup(outputLayoutLabels, nil)
+	layoutRadio.Required = true
+	layoutRadio.SetSelected(outputLayoutLabels[loadDefaultLayout()])
+	selectedLayout := func() outputLayout {
+		for i, label := range outputLayoutLabels {
+			if label == layoutRadio.Selected {
+				return outputLayout(i)
+			}
+		}
+		return layoutSubfolder
+	}
+
+	previewLbl := widget.NewLabel("")
+	previewLbl.Wrapping = fyne.TextWrapWord
+	updatePreview := func() {
+		if dirEntry.Text == "" {
+			previewLbl.SetText("")
+			return
+		}
+		previewLbl.SetText("将解压到: " + planOutput(req, filepath.Clean(dirEntry.Text), selectedLayout()).resultDir())
+	}
+	dirEntry.OnChanged = func(string) { updatePreview() }
+	layoutRadio.OnChanged = func(string) { updatePreview() }
+	updatePreview()
+
+	browseBtn := widget.NewButton("浏览...", func() {
+		fd := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, win)
+				return
+			}
+			if uri == nil {
+				return
+			}
+			dirEntry.SetText(uri.Path())
+		}, win)
+		if lister, err := storage.ListerForURI(storage.NewFileURI(dirEntry.Text)); err == nil {
+			fd.SetLocation(lister)
+		}
+		fd.Show()
+	})
+
+	recentSelect := widget.NewSelect(recent, func(s string) {
+		if s != "" {
+			dirEntry.SetText(s)
+		}
+	})
+	recentSelect.PlaceHolder = "最近使用的文件夹"
+	if len(recent) == 0 {
+		recentSelect.Disable()
+	}
+
+	form := container.NewVBox(
+		widget.NewLabel("目标文件夹:"),
+		container.NewBorder(nil, nil, nil, browseBtn, dirEntry),
+		recentSelect,
+		layoutRadio,
+		previewLbl,
+	)
+	content := container.NewGridWrap(fyne.NewSize(460, form.MinSize().Height+40), form)
+
+	dialog.ShowCustomConfirm("解压到...", "解压", "取消", wrapWithMinSize(content), func(ok bool) {
+		if !ok {
+			return
+		}
+		chosen := filepath.Clean(dirEntry.Text)
+		if dirEntry.Text == "" {
+			dialog.ShowError(errors.New("请选择目标文件夹"), win)
+			return
+		}
+		info, err := os.Stat(chosen)
+		if err != nil || !info.IsDir() {
+			dialog.ShowError(errors.New("目标文件夹不存在: "+chosen), win)
+			return
+		}
+		addRecentDestination(chosen)
+		onConfirm(planOutput(req, chosen, selectedLayout()))
+	}, win)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 磁盘剩余空间检查相关代码
+// ---------------------------------------------------------
+
+// spaceShortage 描述目标位置空间不足的情况
+type spaceShortage struct {
+	dir       string
+	needed    uint64
+	available uint64
+}
+
+func (s spaceShortage) missing() uint64 {
+	return s.needed - s.available
+}
+
+// existingAncestor 返回 dir 自身或最近的已存在的上级目录, 解压目录在解压前可能还没有创建
+func existingAncestor(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// checkFreeSpace 比较将被解压的内容与目标文件系统的可用空间, 空间足够或无法获取时返回 false
+func checkFreeSpace(req extractRequest) (spaceShortage, bool) {
+	var needed uint64
+	for _, it := range selectedItems(req) {
+		needed += it.size
+	}
+	dir := existingAncestor(req.extractDir())
+	available, err := freeSpace(dir)
+	if err != nil || available >= needed {
+		return spaceShortage{}, false
+	}
+	return spaceShortage{dir: dir, needed: needed, available: available}, true
+}
+
+// showSpaceDialog 提示空间不足, 用户可以选择其他位置, 仍然解压或取消
+func showSpaceDialog(win fyne.Window, s spaceShortage, onChooseOther func(), onContinue func(), onCancel func()) {
+	msg := widget.NewLabel(fmt.Sprintf("目标位置空间不足:\n%s\n\n需要 %s, 可用 %s, 还差 %s",
+		s.dir, formatSize(s.needed), formatSize(s.available), formatSize(s.missing())))
+	msg.Wrapping = fyne.TextWrapWord
+	msg.Alignment = fyne.TextAlignCenter
+
+	var d dialog.Dialog
+	finish := func(f func()) func() {
+		return func() {
+			d.Hide()
+			f()
+		}
+	}
+	otherBtn := widget.NewButton("选择其他位置", finish(onChooseOther))
+	otherBtn.Importance = widget.HighImportance
+	continueBtn := widget.NewButton("仍然解压", finish(onContinue))
+	cancelBtn := widget.NewButton("取消", finish(onCancel))
+
+	content := container.NewBorder(nil, container.NewGridWithColumns(3, otherBtn, continueBtn, cancelBtn), nil, nil, wrapWithMinSize(msg))
+	d = dialog.NewCustomWithoutButtons("空间不足", content, win)
+	d.Show()
+}
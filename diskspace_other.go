@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package main
+
+import "errors"
+
+// freeSpace 在无法查询剩余空间的平台上返回错误, checkFreeSpace 会跳过检查
+func freeSpace(dir string) (uint64, error) {
+	return 0, errors.New("当前平台不支持查询剩余空间")
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package main
+
+import "golang.org/x/sys/windows"
+
+// freeSpace 返回 dir 所在磁盘中当前用户可用的字节数
+func freeSpace(dir string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var avail, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &avail, &total, &totalFree); err != nil {
+		return 0, err
+	}
+	return avail, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 7zz 错误分类相关代码
+// ---------------------------------------------------------
+
+// 7zz 的退出码
+const (
+	exitOK          = 0
+	exitWarning     = 1   // 非致命错误, 例如部分文件无法打开
+	exitFatal       = 2   // 致命错误
+	exitBadCommand  = 7   // 命令行错误
+	exitOutOfMemory = 8   // 内存不足
+	exitUserStop    = 255 // 用户中止
+)
+
+type errorKind int
+
+const (
+	errUnknown errorKind = iota
+	errWrongPassword
+	errHeadersEncrypted
+	errUnsupportedMethod
+	errCRC
+	errData
+	errUnexpectedEnd
+	errNotArchive
+	errDiskFull
+	errPermission
+	errBadCommand
+	errOutOfMemory
+	errUserStop
+	errWarning
+)
+
+var errorKindMessages = map[errorKind]string{
+	errUnknown:           "7zz 运行失败",
+	errWrongPassword:     "密码错误",
+	errHeadersEncrypted:  "压缩包的文件名已加密, 需要正确的密码才能打开",
+	errUnsupportedMethod: "不支持该压缩方法, 可能需要更新版本的 7zz",
+	errCRC:               "CRC 校验失败, 压缩包已损坏",
+	errData:              "数据错误, 压缩包已损坏",
+	errUnexpectedEnd:     "压缩包意外结束, 可能没有下载完整或缺少分卷",
+	errNotArchive:        "无法识别为压缩包, 可能不是压缩文件或已损坏",
+	errDiskFull:          "磁盘空间不足",
+	errPermission:        "没有权限读取或写入文件",
+	errBadCommand:        "7zz 命令行参数错误",
+	errOutOfMemory:       "内存不足",
+	errUserStop:          "操作已被中止",
+	errWarning:           "已完成, 但 7zz 报告了警告",
+}
+
+// errorPatterns 按优先级排列, 先匹配到的生效; 例如 "CRC Failed in encrypted file. Wrong password?" 应视为密码错误
+var errorPatterns = []struct {
+	kind     errorKind
+	patterns []string
+}{
+	{errHeadersEncrypted, []string{"can not open encrypted archive", "cannot open encrypted archive"}},
+	{errWrongPassword, []string{"wrong password"}},
+	{errUnsupportedMethod, []string{"unsupported method", "unsupported compression method", "unsupported feature"}},
+	{errDiskFull, []string{"not enough space on the disk", "no space left on device", "disk full"}},
+	{errPermission, []string{"permission denied", "access is denied", "operation not permitted"}},
+	{errOutOfMemory, []string{"can't allocate required memory", "not enough memory", "cannot allocate memory"}},
+	{errCRC, []string{"crc failed", "crc error"}},
+	{errData, []string{"data error", "headers error"}},
+	{errUnexpectedEnd, []string{"unexpected end of archive", "unexpected end of data"}},
+	{errNotArchive, []string{"cannot open the file as archive", "can not open the file as archive", "is not archive"}},
+}
+
+// sevenZipError 是分类后的 7zz 错误, Error 返回给用户看的说明, output 保留原始日志
+type sevenZipError struct {
+	kind     errorKind
+	exitCode int // 无法取得退出码时为 -1
+	output   string
+	err      error
+}
+
+func (e *sevenZipError) Error() string {
+	return errorKindMessages[e.kind]
+}
+
+func (e *sevenZipError) Unwrap() error {
+	return e.err
+}
+
+// exitCodeOf 返回 err 中的退出码, 没有时返回 -1
+func exitCodeOf(err error) int {
+	var coder interface{ ExitCode() int }
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+	return -1
+}
+
+// diagnosticLines 返回 7zz 输出中的诊断行: "ERROR: ..." (含 "Open ERROR:" 等), "Can not open ..." 行,
+// 以及 "ERRORS:" 这类单独标题行之后直到空行的内容; 列表中 "Path = ..." 这样的键值行不算, 以免文件名被误认成错误
+func diagnosticLines(output string) []string {
+	var lines []string
+	inBlock := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		lower := strings.ToLower(line)
+		switch {
+		case line == "":
+			inBlock = false
+		case strings.HasPrefix(lower, "can not open") || strings.HasPrefix(lower, "cannot open"):
+			lines = append(lines, lower)
+		case isErrorHeader(lower):
+			lines = append(lines, lower)
+			inBlock = strings.HasSuffix(lower, ":")
+		case inBlock && !strings.Contains(line, " = "):
+			lines = append(lines, lower)
+		}
+	}
+	return lines
+}
+
+// isErrorHeader 判断小写的一行是否以 "error:" / "errors:" 开头, 允许前面有一个单词, 例如 "open error:"
+func isErrorHeader(lower string) bool {
+	if _, rest, ok := strings.Cut(lower, " "); ok && !strings.HasPrefix(lower, "error") {
+		lower = rest
+	}
+	return strings.HasPrefix(lower, "error:") || strings.HasPrefix(lower, "errors:")
+}
+
+// classifyOutput 根据 7zz 诊断行中已知的提示判断错误类型, 没有匹配时返回 errUnknown
+func classifyOutput(output string) errorKind {
+	lines := diagnosticLines(output)
+	for _, p := range errorPatterns {
+		for _, pattern := range p.patterns {
+			for _, line := range lines {
+				if strings.Contains(line, pattern) {
+					return p.kind
+				}
+			}
+		}
+	}
+	return errUnknown
+}
+
+// classify7zzError 把 7zz 的输出和退出错误转换为 *sevenZipError, 运行成功时返回 nil
+// 输出中的提示比退出码更具体, 优先使用; 都无法判断时按退出码归类
+func classify7zzError(output string, err error) *sevenZipError {
+	if err == nil {
+		return nil
+	}
+	e := &sevenZipError{kind: classifyOutput(output), exitCode: exitCodeOf(err), output: output, err: err}
+	if e.kind != errUnknown {
+		return e
+	}
+	switch e.exitCode {
+	case exitWarning:
+		e.kind = errWarning
+	case exitBadCommand:
+		e.kind = errBadCommand
+	case exitOutOfMemory:
+		e.kind = errOutOfMemory
+	case exitUserStop:
+		e.kind = errUserStop
+	}
+	return e
+}
+
+// needsPassword 判断 7zz 是否因为缺少或输错密码而失败, 运行成功 (err 为 nil) 时总是返回 false
+func needsPassword(output string, err error) bool {
+	if err == nil {
+		return false
+	}
+	switch classifyOutput(output) {
+	case errWrongPassword, errHeadersEncrypted:
+		return true
+	}
+	return false
+}
+
+// show7zzError 显示 7zz 错误, title 为操作名称, 原始日志放在可展开的"详细信息"中
+func show7zzError(win fyne.Window, title string, output string, err error) {
+	e := classify7zzError(output, err)
+	if e == nil {
+		e = &sevenZipError{kind: classifyOutput(output), exitCode: -1, output: output}
+	}
+	msg := widget.NewLabel(e.Error())
+	msg.Wrapping = fyne.TextWrapWord
+	msg.Alignment = fyne.TextAlignCenter
+
+	log := strings.TrimSpace(e.output)
+	if log == "" && e.err != nil {
+		log = e.err.Error()
+	}
+	details := widget.NewAccordion(widget.NewAccordionItem("详细信息", container.NewGridWrap(
+		fyne.NewSize(WINDOW_WIDTH*0.6, WINDOW_HEIGHT*0.3), readOnlyEntry(log))))
+
+	dialog.ShowCustom(title, "确定", container.NewVBox(wrapWithMinSize(msg), details), win)
+}
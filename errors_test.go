@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassify7zzError(t *testing.T) {
+	cases := []struct {
+		name   string
+		output string
+		err    error
+		want   errorKind
+	}{
+		{"文件名已加密", "ERROR: broken.7z\nCan not open encrypted archive. Wrong password?", &fakeExitError{code: exitFatal}, errHeadersEncrypted},
+		{"加密文件的 CRC 错误视为密码错误", "ERROR: CRC Failed in encrypted file. Wrong password? : a.txt", &fakeExitError{code: exitFatal}, errWrongPassword},
+		{"CRC 错误", "ERROR: CRC Failed : a.txt", &fakeExitError{code: exitFatal}, errCRC},
+		{"磁盘已满", "ERROR: No space left on device : a.txt", &fakeExitError{code: exitFatal}, errDiskFull},
+		{"不是压缩包", "ERROR: a.bin\nCannot open the file as archive", &fakeExitError{code: exitFatal}, errNotArchive},
+		{"打开时的错误", "Open ERROR: Can not open the file as archive", &fakeExitError{code: exitFatal}, errNotArchive},
+		{"ERRORS 标题之后的错误", "ERRORS:\nUnexpected end of archive\n", &fakeExitError{code: exitFatal}, errUnexpectedEnd},
+		{"退出码 1 为警告", "WARNING: Cannot open file", &fakeExitError{code: exitWarning}, errWarning},
+		{"退出码 2 且无法识别", "", &fakeExitError{code: exitFatal}, errUnknown},
+		{"命令行错误", "", &fakeExitError{code: exitBadCommand}, errBadCommand},
+		{"内存不足", "", &fakeExitError{code: exitOutOfMemory}, errOutOfMemory},
+		{"用户中止", "", &fakeExitError{code: exitUserStop}, errUserStop},
+		{"没有退出码", "", errors.New("broken pipe"), errUnknown},
+	}
+	for _, c := range cases {
+		e := classify7zzError(c.output, c.err)
+		if e == nil || e.kind != c.want {
+			t.Errorf("%s: classify7zzError = %+v, want kind %d", c.name, e, c.want)
+			continue
+		}
+		if want := exitCodeOf(c.err); e.exitCode != want {
+			t.Errorf("%s: exitCode = %d, want %d", c.name, e.exitCode, want)
+		}
+	}
+	if e := classify7zzError("Everything is Ok", nil); e != nil {
+		t.Errorf("运行成功时 classify7zzError = %+v, want nil", e)
+	}
+}
+
+// TestClassifyIgnoresListedNames 条目以错误提示命名时, 列表中的 "Path = " 行不能被当作错误
+func TestClassifyIgnoresListedNames(t *testing.T) {
+	for _, p := range errorPatterns {
+		for _, pattern := range p.patterns {
+			listing := "Listing archive: a.7z\n\n----------\nPath = docs/" + pattern + ".txt\nSize = 10\n\nPath = " + pattern + "\nSize = 0\n"
+			if needsPassword(listing, nil) {
+				t.Errorf("%q: 列表成功时 needsPassword = true", pattern)
+			}
+			if got := classifyOutput(listing); got != errUnknown {
+				t.Errorf("%q: classifyOutput = %d, want errUnknown", pattern, got)
+			}
+			if e := classify7zzError(listing, &fakeExitError{code: exitWarning}); e.kind != errWarning {
+				t.Errorf("%q: classify7zzError kind = %d, want errWarning", pattern, e.kind)
+			}
+		}
+	}
+}
+
+func TestIsPartialSuccess(t *testing.T) {
+	entry := []extractWarning{{name: "a.txt", reason: "CRC Failed"}}
+	archive := []extractWarning{{reason: "Unexpected end of archive"}}
+	cases := []struct {
+		name     string
+		err      error
+		warnings []extractWarning
+		want     bool
+	}{
+		{"退出码 1", &fakeExitError{code: exitWarning}, nil, true},
+		{"退出码 2 且有条目错误", &fakeExitError{code: exitFatal}, entry, true},
+		{"退出码 2 且只有整体错误", &fakeExitError{code: exitFatal}, archive, false},
+		{"命令行错误", &fakeExitError{code: exitBadCommand}, entry, false},
+		{"成功", nil, nil, false},
+	}
+	for _, c := range cases {
+		if got := isPartialSuccess(c.err, c.warnings); got != c.want {
+			t.Errorf("%s: isPartialSuccess = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ---------------------------------------------------------
+// 回放录制输出的假后端, 用于在没有 7zz 的环境中驱动界面流程
+// ---------------------------------------------------------
+
+// 假后端的操作名与 7zz 的命令字母一致, ExtractFile 和 Preview 都对应 e
+const (
+	fakeOpList        = "l"
+	fakeOpExtract     = "x"
+	fakeOpTest        = "t"
+	fakeOpAdd         = "a"
+	fakeOpExtractFile = "e"
+)
+
+// fakeNotFound 作为退出码时模拟找不到 7zz
+const fakeNotFound = -1
+
+// fakeResponse 是录制的一次 7zz 调用结果, output 为标准输出与标准错误合并后的内容
+type fakeResponse struct {
+	output    string
+	exitCode  int
+	unwrapTar bool   // 仅用于 List
+	data      string // ExtractFile 写入的文件内容, Preview 读到的内容
+}
+
+// fakeCall 记录假后端收到的一次调用
+type fakeCall struct {
+	op          string
+	archivePath string
+	password    string // 记录时复制一份, 调用方随后清零也不影响断言
+	codePage    int
+	paths       []string
+}
+
+// fakeExitError 模拟 7zz 以非零退出码结束
+type fakeExitError struct {
+	code int
+}
+
+func (e *fakeExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// ExitCode 与 exec.ExitError 一致, 供 exitCodeOf 读取
+func (e *fakeExitError) ExitCode() int {
+	return e.code
+}
+
+// fakeArchiver 按操作和压缩包路径回放录制的输出, 同一个键录制了多次时依次回放, 最后一次会一直重复
+// 调用方在 fyne.Do 中更新界面后才取消 ctx, 因此 ctx 结束时界面已处理完这次调用的结果, 见 waitCalls
+type fakeArchiver struct {
+	mu        sync.Mutex
+	responses map[string][]fakeResponse
+	calls     []fakeCall
+	active    sync.WaitGroup // 还没有结束的调用
+}
+
+func newFakeArchiver() *fakeArchiver {
+	return &fakeArchiver{responses: make(map[string][]fakeResponse)}
+}
+
+func fakeKey(op string, archivePath string) string {
+	return op + "\x00" + archivePath
+}
+
+// record 录制一次调用结果, Add 的 archivePath 为要创建的压缩包路径
+func (f *fakeArchiver) record(op string, archivePath string, resp fakeResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := fakeKey(op, archivePath)
+	f.responses[key] = append(f.responses[key], resp)
+}
+
+// recordFile 从文件读取录制的输出, 例如保存下来的 7zz l -slt 结果
+func (f *fakeArchiver) recordFile(op string, archivePath string, file string, exitCode int) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	f.record(op, archivePath, fakeResponse{output: string(data), exitCode: exitCode})
+	return nil
+}
+
+// history 返回到目前为止收到的调用
+func (f *fakeArchiver) history() []fakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeCall(nil), f.calls...)
+}
+
+// waitCalls 等待收到 n 次调用, 并且调用方都已处理完结果
+// fyne/test 的驱动直接在调用 fyne.Do 的 goroutine 中运行界面代码, 测试只能在此之后读取界面状态
+func (f *fakeArchiver) waitCalls(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(f.history()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待第 %d 次调用超时, 已收到 %d 次", n, len(f.history()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	f.active.Wait()
+}
+
+// replay 取出下一条录制结果, 像真实的 7zz 一样逐行回调进度并过滤掉进度行
+func (f *fakeArchiver) replay(ctx context.Context, call fakeCall, onProgress func(progressInfo)) (fakeResponse, string, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.active.Add(1)
+	go func() {
+		<-ctx.Done()
+		f.active.Done()
+	}()
+	key := fakeKey(call.op, call.archivePath)
+	queue := f.responses[key]
+	var resp fakeResponse
+	ok := len(queue) > 0
+	if ok {
+		resp = queue[0]
+		if len(queue) > 1 {
+			f.responses[key] = queue[1:]
+		}
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		return resp, "", fmt.Errorf("没有录制 %s %s 的输出", call.op, call.archivePath)
+	}
+	if resp.exitCode == fakeNotFound {
+		return resp, "", &exec.Error{Name: sevenZipPath, Err: exec.ErrNotFound}
+	}
+
+	out := newOutputCollector(func(line string) {
+		if info, ok := parse7zzProgress(line); ok && onProgress != nil {
+			onProgress(info)
+		}
+	}, nil)
+	_, _ = io.WriteString(out, resp.output)
+	output := out.finish()
+
+	if err := ctx.Err(); err != nil {
+		return resp, output, err
+	}
+	if resp.exitCode != 0 {
+		return resp, output, &fakeExitError{code: resp.exitCode}
+	}
+	return resp, output, nil
+}
+
+func (f *fakeArchiver) List(ctx context.Context, archivePath string, password []byte, codePage int, onStall func(stalled bool)) (string, bool, error) {
+	resp, output, err := f.replay(ctx, fakeCall{op: fakeOpList, archivePath: archivePath, password: string(password), codePage: codePage}, nil)
+	return output, resp.unwrapTar, err
+}
+
+func (f *fakeArchiver) Extract(ctx context.Context, req extractRequest, onProgress func(progressInfo), onStall func(stalled bool)) (string, error) {
+	_, output, err := f.replay(ctx, fakeCall{op: fakeOpExtract, archivePath: req.archivePath, password: string(req.password), codePage: req.codePage, paths: req.paths}, onProgress)
+	return output, err
+}
+
+func (f *fakeArchiver) Test(ctx context.Context, archivePath string, password []byte, codePage int, paths []string, onProgress func(progressInfo), onStall func(stalled bool)) (string, error) {
+	_, output, err := f.replay(ctx, fakeCall{op: fakeOpTest, archivePath: archivePath, password: string(password), codePage: codePage, paths: paths}, onProgress)
+	return output, err
+}
+
+func (f *fakeArchiver) Add(ctx context.Context, req createRequest, onProgress func(progressInfo), onStall func(stalled bool)) (string, error) {
+	_, output, err := f.replay(ctx, fakeCall{op: fakeOpAdd, archivePath: req.output, paths: req.inputs}, onProgress)
+	return output, err
+}
+
+func (f *fakeArchiver) ExtractFile(ctx context.Context, archivePath string, password []byte, codePage int, unwrapTar bool, name string, dir string, onProgress func(progressInfo), onStall func(stalled bool)) (string, string, error) {
+	resp, output, err := f.replay(ctx, fakeCall{op: fakeOpExtractFile, archivePath: archivePath, password: string(password), codePage: codePage, paths: []string{name}}, onProgress)
+	if err != nil {
+		return "", output, err
+	}
+	dst := filepath.Join(dir, filepath.Base(filepath.FromSlash(name)))
+	return dst, output, os.WriteFile(dst, []byte(resp.data), 0o644)
+}
+
+func (f *fakeArchiver) Preview(ctx context.Context, archivePath string, password []byte, codePage int, unwrapTar bool, name string, limit uint64) ([]byte, bool, string, error) {
+	resp, output, err := f.replay(ctx, fakeCall{op: fakeOpExtractFile, archivePath: archivePath, password: string(password), codePage: codePage, paths: []string{name}}, nil)
+	if err != nil {
+		return nil, false, output, err
+	}
+	data := []byte(resp.data)
+	if uint64(len(data)) > limit {
+		return data[:limit], true, output, nil
+	}
+	return data, false, output, nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 列表搜索与过滤相关代码
+// ---------------------------------------------------------
+
+type filterMode int
+
+const (
+	filterSubstring filterMode = iota // 不区分大小写的包含匹配
+	filterGlob                        // 通配符, 例如 *.log
+	filterRegex                       // 正则表达式, 匹配完整路径
+)
+
+var filterModeLabels = []string{"包含", "通配符", "正则"}
+
+type filterKind int
+
+const (
+	filterAll filterKind = iota
+	filterFiles
+	filterDirs
+)
+
+var filterKindLabels = []string{"全部", "仅文件", "仅文件夹"}
+
+// sizeRanges 是大小过滤的快捷选项, max 为 0 表示不限上限
+var sizeRanges = []struct {
+	label    string
+	min, max uint64
+}{
+	{"任意大小", 0, 0},
+	{"小于 1MB", 0, 1 << 20},
+	{"1MB - 100MB", 1 << 20, 100 << 20},
+	{"100MB - 1GB", 100 << 20, 1 << 30},
+	{"大于 1GB", 1 << 30, 0},
+}
+
+// listFilter 描述当前的过滤条件, 启用时列表显示当前目录整个子树中匹配的条目
+type listFilter struct {
+	text      string
+	mode      filterMode
+	kind      filterKind
+	sizeRange int
+
+	lower string         // 包含匹配使用的小写文本
+	re    *regexp.Regexp // 正则模式下编译好的表达式, 表达式无效时为 nil
+}
+
+// compile 根据 text 与 mode 准备匹配所需的数据, 正则无效时返回错误且忽略文本条件
+func (f *listFilter) compile() error {
+	f.lower = strings.ToLower(f.text)
+	f.re = nil
+	if f.mode != filterRegex || f.text == "" {
+		return nil
+	}
+	re, err := regexp.Compile("(?i)" + f.text)
+	if err != nil {
+		return err
+	}
+	f.re = re
+	return nil
+}
+
+func (f *listFilter) hasText() bool {
+	if f.mode == filterRegex {
+		return f.re != nil
+	}
+	return f.text != ""
+}
+
+func (f *listFilter) active() bool {
+	return f.hasText() || f.kind != filterAll || f.sizeRange != 0
+}
+
+// match 判断节点是否满足过滤条件
+// 包含与通配符模式在文本不含 / 时只匹配名称, 含 / 时匹配完整路径; 正则总是匹配完整路径
+func (f *listFilter) match(n *treeNode) bool {
+	switch f.kind {
+	case filterFiles:
+		if n.item.isDir {
+			return false
+		}
+	case filterDirs:
+		if !n.item.isDir {
+			return false
+		}
+	}
+
+	r := sizeRanges[f.sizeRange]
+	if n.totalSize < r.min || (r.max > 0 && n.totalSize >= r.max) {
+		return false
+	}
+
+	if !f.hasText() {
+		return true
+	}
+	target := n.name
+	if strings.Contains(f.text, "/") {
+		target = n.item.name
+	}
+	switch f.mode {
+	case filterGlob:
+		ok, _ := path.Match(f.lower, strings.ToLower(target))
+		return ok
+	case filterRegex:
+		return f.re.MatchString(n.item.name)
+	}
+	return strings.Contains(strings.ToLower(target), f.lower)
+}
+
+// refreshRows 根据当前目录, 过滤条件和排序方式重新计算显示的行
+func (b *archiveBrowser) refreshRows() {
+	b.anchor = -1
+	if b.current == nil {
+		b.rows = nil
+		return
+	}
+	if !b.filter.active() {
+		b.rows = b.current.children
+		if b.sortCol != sortNone {
+			b.rows = append([]*treeNode(nil), b.rows...)
+			b.sortRows(b.rows)
+		}
+		return
+	}
+	rows := make([]*treeNode, 0, 256)
+	var walk func(n *treeNode)
+	walk = func(n *treeNode) {
+		for _, c := range n.children {
+			if b.filter.match(c) {
+				rows = append(rows, c)
+			}
+			walk(c)
+		}
+	}
+	walk(b.current)
+	b.sortRows(rows)
+	b.rows = rows
+}
+
+// displayName 返回行中显示的名称, 过滤时显示相对当前目录的路径以便区分同名文件
+func (b *archiveBrowser) displayName(n *treeNode) string {
+	if !b.filter.active() || n.parent == b.current {
+		return n.name
+	}
+	parts := []string{n.name}
+	for p := n.parent; p != nil && p != b.current; p = p.parent {
+		parts = append(parts, p.name)
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, "/")
+}
+
+// setFilter 更新过滤条件并刷新列表, 进入其他目录时条件保持不变
+func (b *archiveBrowser) setFilter(f listFilter) {
+	b.filter = f
+	b.refreshRows()
+	b.list.UnselectAll()
+	b.list.ScrollToTop()
+	b.list.Refresh()
+}
+
+// newFilterBar 创建列表上方的过滤栏, 输入时立即更新列表
+func newFilterBar(b *archiveBrowser) fyne.CanvasObject {
+	entry := widget.NewEntry()
+	entry.PlaceHolder = "搜索名称, 例如 report 或 *.log"
+	modeSel := widget.NewSelect(filterModeLabels, nil)
+	kindSel := widget.NewSelect(filterKindLabels, nil)
+	sizeLabels := make([]string, len(sizeRanges))
+	for i, r := range sizeRanges {
+		sizeLabels[i] = r.label
+	}
+	sizeSel := widget.NewSelect(sizeLabels, nil)
+	errLbl := widget.NewLabel("正则表达式无效")
+	errLbl.Importance = widget.DangerImportance
+	errLbl.Hide()
+
+	apply := func() {
+		f := listFilter{
+			text:      entry.Text,
+			mode:      filterMode(modeSel.SelectedIndex()),
+			kind:      filterKind(kindSel.SelectedIndex()),
+			sizeRange: sizeSel.SelectedIndex(),
+		}
+		if f.mode < 0 {
+			f.mode = filterSubstring
+		}
+		if f.kind < 0 {
+			f.kind = filterAll
+		}
+		if f.sizeRange < 0 {
+			f.sizeRange = 0
+		}
+		if err := f.compile(); err != nil {
+			errLbl.Show()
+		} else {
+			errLbl.Hide()
+		}
+		b.setFilter(f)
+	}
+
+	modeSel.SetSelectedIndex(0)
+	kindSel.SetSelectedIndex(0)
+	sizeSel.SetSelectedIndex(0)
+	// 输入时稍等片刻再过滤, 条目很多时连续输入不会卡顿
+	var debounce *time.Timer
+	entry.OnChanged = func(string) {
+		if debounce != nil {
+			debounce.Stop()
+		}
+		debounce = time.AfterFunc(FILTER_DEBOUNCE_MS*time.Millisecond, func() { fyne.Do(apply) })
+	}
+	modeSel.OnChanged = func(string) { apply() }
+	kindSel.OnChanged = func(string) { apply() }
+	sizeSel.OnChanged = func(string) { apply() }
+
+	clearBtn := widget.NewButton("清除", func() {
+		entry.SetText("")
+		kindSel.SetSelectedIndex(0)
+		sizeSel.SetSelectedIndex(0)
+	})
+	clearBtn.Importance = widget.LowImportance
+
+	return container.NewBorder(nil, nil, nil, container.NewHBox(errLbl, modeSel, kindSel, sizeSel, clearBtn), entry)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path"
+	"testing"
+)
+
+func TestListFilterMatch(t *testing.T) {
+	file := func(name string, size uint64) *treeNode {
+		return &treeNode{item: archiveItem{name: name, size: size}, name: path.Base(name), totalSize: size}
+	}
+	readme := file("docs/ReadMe.txt", 1200)
+	log := file("logs/2024/app.log", 200<<20)
+	dir := &treeNode{item: archiveItem{name: "docs", isDir: true}, name: "docs", totalSize: 1200}
+
+	cases := []struct {
+		name   string
+		filter listFilter
+		node   *treeNode
+		want   bool
+	}{
+		{"包含不区分大小写", listFilter{text: "readme"}, readme, true},
+		{"包含只匹配名称", listFilter{text: "docs"}, readme, false},
+		{"含 / 时匹配完整路径", listFilter{text: "docs/read"}, readme, true},
+		{"通配符匹配名称", listFilter{text: "*.LOG", mode: filterGlob}, log, true},
+		{"通配符需要整体匹配", listFilter{text: "app", mode: filterGlob}, log, false},
+		{"通配符含 / 时匹配完整路径", listFilter{text: "logs/*/*.log", mode: filterGlob}, log, true},
+		{"正则匹配完整路径", listFilter{text: `^logs/\d+/`, mode: filterRegex}, log, true},
+		{"正则不区分大小写", listFilter{text: `readme\.TXT$`, mode: filterRegex}, readme, true},
+		{"无效的正则忽略文本条件", listFilter{text: `(`, mode: filterRegex}, readme, true},
+		{"仅文件排除文件夹", listFilter{kind: filterFiles}, dir, false},
+		{"仅文件夹排除文件", listFilter{kind: filterDirs}, readme, false},
+		{"仅文件夹按名称匹配", listFilter{text: "doc", kind: filterDirs}, dir, true},
+		{"小于 1MB", listFilter{sizeRange: 1}, readme, true},
+		{"小于 1MB 不含 1MB", listFilter{sizeRange: 1}, file("a.bin", 1<<20), false},
+		{"1MB - 100MB 含下限", listFilter{sizeRange: 2}, file("a.bin", 1<<20), true},
+		{"100MB - 1GB", listFilter{sizeRange: 3}, log, true},
+		{"大于 1GB 没有上限", listFilter{sizeRange: 4}, file("big.iso", 8<<30), true},
+		{"大小与文本同时满足", listFilter{text: "app", sizeRange: 1}, log, false},
+	}
+	for _, c := range cases {
+		f := c.filter
+		_ = f.compile()
+		if got := f.match(c.node); got != c.want {
+			t.Errorf("%s: match = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 压缩包完整性测试相关代码
+// ---------------------------------------------------------
+
+const prefKeyTestBeforeExtract = "testBeforeExtract"
+
+// testEntryError 是测试中某个条目的错误
+type testEntryError struct {
+	name   string
+	reason string
+}
+
+// testReport 是 7zz t 的测试结果
+type testReport struct {
+	files         int              // 7zz 报告的文件数
+	entryErrors   []testEntryError // 出错的条目, 例如 CRC 错误或数据错误
+	archiveErrors []string         // 与具体条目无关的错误, 例如头部损坏或意外结束
+}
+
+func (r testReport) ok() bool {
+	return len(r.entryErrors) == 0 && len(r.archiveErrors) == 0
+}
+
+func (r testReport) crcErrors() int {
+	n := 0
+	for _, e := range r.entryErrors {
+		if strings.Contains(strings.ToLower(e.reason), "crc") {
+			n++
+		}
+	}
+	return n
+}
+
+// parse7zzTest 解析 7zz t 的输出
+// 条目错误形如 "ERROR: CRC Failed : dir/file.txt", 没有 " : " 的 ERROR 行视为压缩包级别的错误
+func parse7zzTest(output string) testReport {
+	var r testReport
+	for _, raw := range strings.Split(output, "\n") {
+		line := strings.TrimSpace(raw)
+		switch {
+		case strings.HasPrefix(line, "ERROR:"):
+			msg := strings.TrimSpace(strings.TrimPrefix(line, "ERROR:"))
+			if idx := strings.LastIndex(msg, " : "); idx != -1 {
+				r.entryErrors = append(r.entryErrors, testEntryError{
+					name:   strings.TrimSpace(msg[idx+3:]),
+					reason: strings.TrimSpace(msg[:idx]),
+				})
+			} else if msg != "" {
+				r.archiveErrors = append(r.archiveErrors, msg)
+			}
+		case strings.HasPrefix(line, "Files:"):
+			if n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Files:"))); err == nil {
+				r.files = n
+			}
+		case strings.HasPrefix(line, "Unexpected end of archive"),
+			strings.HasPrefix(line, "Headers Error"),
+			strings.HasPrefix(line, "Is not archive"):
+			r.archiveErrors = append(r.archiveErrors, line)
+		}
+	}
+	return r
+}
+
+func run7zzTest(ctx context.Context, archivePath string, password []byte, codePage int, paths []string, onProgress func(progressInfo), onStall func(stalled bool)) (string, error) {
+	args := append([]string{"t", archivePath, "-bsp1", "-bso1"}, codePageArgs(codePage)...)
+	args, cleanup, err := appendPathArgs(args, paths)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	return run7zzStream(ctx, func(line string) {
+		if info, ok := parse7zzProgress(line); ok && onProgress != nil {
+			onProgress(info)
+		}
+	}, onStall, password, args...)
+}
+
+// runArchiveTest 在进度面板中测试压缩包, 需要密码时会提示输入
+// 测试真正完成后 (无论通过与否) 在 UI 线程调用 onDone, 取消或无法运行时调用 onAbort
+// password 归本次测试所有, 结束时清零, 或在 7zz 没有报告密码错误时交给 currentPassword
+func runArchiveTest(win fyne.Window, token uint64, archivePath string, password []byte, codePage int, paths []string, totalSize uint64, panel *taskProgress, onDone func(report testReport), onAbort func()) {
+	ctx, cancel := context.WithCancel(sessionCtx)
+	panel.start(totalSize, cancel)
+
+	go func() {
+		defer cancel()
+
+		lastPercent := -1
+		output, err := archiver.Test(ctx, archivePath, password, codePage, paths, func(info progressInfo) {
+			if info.percent == lastPercent && info.file == "" {
+				return
+			}
+			lastPercent = info.percent
+			fyne.Do(func() {
+				if token != dropCounter.Load() {
+					return
+				}
+				panel.update(info)
+			})
+		}, newStallPrompt(win, cancel, func() bool { return token == dropCounter.Load() }))
+		canceled := ctx.Err() != nil
+
+		fyne.Do(func() {
+			keep := false
+			defer func() {
+				if !keep {
+					clear(password)
+				}
+			}()
+			if token != dropCounter.Load() || archivePath != currentFile {
+				return
+			}
+			panel.stop()
+
+			switch {
+			case canceled:
+				dialog.ShowInformation("已取消", "已取消测试", win)
+				onAbort()
+			case err != nil && is7zzNotFound(err):
+				dialog.ShowError(fmt.Errorf("找不到 7zz.\n请把 7zz 文件和本程序放在同一个文件夹.\n当前尝试路径: %s", sevenZipPath), win)
+				onAbort()
+			case needsPassword(output, err):
+				askPassword(sessionCtx, win, archivePath, codePage, nil, func(pwd []byte) {
+					if token != dropCounter.Load() {
+						clear(pwd)
+						return
+					}
+					runArchiveTest(win, token, archivePath, pwd, codePage, paths, totalSize, panel, onDone, onAbort)
+				}, onAbort)
+			default:
+				// 7zz 没有报告密码错误, 之后的解压沿用该密码; 测试完全通过时才记入缓存
+				if len(password) > 0 {
+					if err == nil {
+						rememberPassword(archivePath, password)
+					}
+					setCurrentPassword(password)
+					keep = true
+				}
+				report := parse7zzTest(output)
+				if err != nil && report.ok() {
+					report.archiveErrors = append(report.archiveErrors, strings.TrimSpace(output))
+				}
+				onDone(report)
+			}
+		})
+	}()
+}
+
+// showTestReport 显示测试结果, onContinue 不为 nil 时提供"仍然解压"按钮
+func showTestReport(win fyne.Window, archivePath string, report testReport, onContinue func(), onClose func()) {
+	if report.ok() {
+		if onContinue != nil {
+			onContinue()
+			return
+		}
+		msgLabel := widget.NewLabel(fmt.Sprintf("测试通过: %s\n共 %d 个文件, 未发现错误", filepath.Base(archivePath), report.files))
+		msgLabel.Wrapping = fyne.TextWrapWord
+		msgLabel.Alignment = fyne.TextAlignCenter
+		d := dialog.NewCustom("测试通过", "确定", wrapWithMinSize(msgLabel), win)
+		d.SetOnClosed(onClose)
+		d.Show()
+		return
+	}
+
+	summary := fmt.Sprintf("测试未通过: %s\n%d 个条目出错, 其中 CRC 错误 %d 个", filepath.Base(archivePath), len(report.entryErrors), report.crcErrors())
+	summaryLbl := widget.NewLabel(summary)
+	summaryLbl.Wrapping = fyne.TextWrapWord
+
+	lines := make([]string, 0, len(report.archiveErrors)+len(report.entryErrors))
+	lines = append(lines, report.archiveErrors...)
+	for _, e := range report.entryErrors {
+		lines = append(lines, e.name+"    "+e.reason)
+	}
+	list := widget.NewList(
+		func() int { return len(lines) },
+		func() fyne.CanvasObject {
+			lbl := widget.NewLabel("")
+			lbl.Truncation = fyne.TextTruncateEllipsis
+			return lbl
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(lines[id])
+		},
+	)
+	content := container.NewBorder(summaryLbl, nil, nil, nil, list)
+
+	var d dialog.Dialog
+	if onContinue != nil {
+		d = dialog.NewCustomConfirm("测试未通过", "仍然解压", "取消", content, func(ok bool) {
+			if ok {
+				onContinue()
+				return
+			}
+			onClose()
+		}, win)
+	} else {
+		d = dialog.NewCustom("测试未通过", "确定", content, win)
+		d.SetOnClosed(onClose)
+	}
+	d.Resize(fyne.NewSize(WINDOW_WIDTH*0.7, WINDOW_HEIGHT*0.6))
+	d.Show()
+}
+
+// appendPathArgs 把压缩包内路径加到 7zz 参数末尾, 数量较多时改用 -i@listfile
+// 返回的 cleanup 用于删除临时列表文件
+func appendPathArgs(args []string, paths []string) ([]string, func(), error) {
+	return appendSelectionArgs(args, paths, nil)
+}
+
+// appendSelectionArgs 把要包含的路径和要跳过的路径加到 7zz 参数末尾, 数量较多时分别写入 -i@ 和 -x@ 列表文件
+// 7zz 不允许 -scs 重复出现, 两个列表文件共用一个 -scsUTF-8; 返回的 cleanup 用于删除临时列表文件
+func appendSelectionArgs(args []string, paths []string, excludes []string) ([]string, func(), error) {
+	var listFiles []string
+	cleanup := func() {
+		for _, f := range listFiles {
+			_ = os.Remove(f)
+		}
+	}
+	addListFile := func(prefix string, list []string) error {
+		listFile, err := writeListFile(list)
+		if err != nil {
+			return err
+		}
+		if len(listFiles) == 0 {
+			args = append(args, "-scsUTF-8")
+		}
+		listFiles = append(listFiles, listFile)
+		args = append(args, prefix+listFile)
+		return nil
+	}
+
+	if len(excludes) > EXTRACT_LISTFILE_THRESHOLD {
+		if err := addListFile("-x@", excludes); err != nil {
+			cleanup()
+			return args, func() {}, err
+		}
+	} else {
+		for _, p := range excludes {
+			args = append(args, "-x!"+p)
+		}
+	}
+
+	if len(paths) > EXTRACT_LISTFILE_THRESHOLD {
+		if err := addListFile("-i@", paths); err != nil {
+			cleanup()
+			return args, func() {}, err
+		}
+	} else if len(paths) > 0 {
+		args = append(args, "--")
+		args = append(args, paths...)
+	}
+	return args, cleanup, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse7zzTest(t *testing.T) {
+	output := `Testing archive: broken.7z
+--
+Path = broken.7z
+Type = 7z
+
+ERROR: CRC Failed : docs/a.txt
+ERROR: Data Error : docs/b.txt
+ERROR: There are some data after the end of the payload data
+Unexpected end of archive
+
+Sub items Errors: 2
+
+Archives with Errors: 1
+
+Files: 5
+Size:       1024
+Compressed: 512
+`
+	r := parse7zzTest(output)
+	if r.ok() {
+		t.Fatal("有错误的测试结果不应视为通过")
+	}
+	if r.files != 5 {
+		t.Errorf("files = %d, want 5", r.files)
+	}
+	wantEntries := []testEntryError{
+		{name: "docs/a.txt", reason: "CRC Failed"},
+		{name: "docs/b.txt", reason: "Data Error"},
+	}
+	if !reflect.DeepEqual(r.entryErrors, wantEntries) {
+		t.Errorf("entryErrors = %+v, want %+v", r.entryErrors, wantEntries)
+	}
+	wantArchive := []string{"There are some data after the end of the payload data", "Unexpected end of archive"}
+	if !reflect.DeepEqual(r.archiveErrors, wantArchive) {
+		t.Errorf("archiveErrors = %q, want %q", r.archiveErrors, wantArchive)
+	}
+	if n := r.crcErrors(); n != 1 {
+		t.Errorf("crcErrors() = %d, want 1", n)
+	}
+
+	if r := parse7zzTest("Everything is Ok\n\nFiles: 3\n"); !r.ok() || r.files != 3 {
+		t.Errorf("正常的测试结果解析为 %+v", r)
+	}
+}
+
+func TestAppendSelectionArgs(t *testing.T) {
+	many := func(prefix string) []string {
+		paths := make([]string, EXTRACT_LISTFILE_THRESHOLD+1)
+		for i := range paths {
+			paths[i] = fmt.Sprintf("%s/%d.txt", prefix, i)
+		}
+		return paths
+	}
+	cases := []struct {
+		name      string
+		paths     []string
+		excludes  []string
+		listFiles int
+	}{
+		{"都较少", []string{"a.txt"}, []string{"b.txt"}, 0},
+		{"选中项较多", many("docs"), []string{"b.txt"}, 1},
+		{"跳过项较多", []string{"a.txt"}, many("skip"), 1},
+		{"都较多", many("docs"), many("skip"), 2},
+	}
+	for _, c := range cases {
+		args, cleanup, err := appendSelectionArgs([]string{"x", "a.7z"}, c.paths, c.excludes)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var scs, lists int
+		var files []string
+		for _, a := range args {
+			switch {
+			case a == "-scsUTF-8":
+				scs++
+			case strings.HasPrefix(a, "-i@"), strings.HasPrefix(a, "-x@"):
+				lists++
+				files = append(files, a[3:])
+			}
+		}
+		wantScs := 0
+		if c.listFiles > 0 {
+			wantScs = 1
+		}
+		if scs != wantScs || lists != c.listFiles {
+			t.Errorf("%s: -scsUTF-8 出现 %d 次, 列表文件 %d 个; want %d, %d", c.name, scs, lists, wantScs, c.listFiles)
+		}
+		cleanup()
+		for _, f := range files {
+			if _, err := os.Stat(f); !os.IsNotExist(err) {
+				t.Errorf("%s: cleanup 后列表文件 %s 仍然存在", c.name, f)
+			}
+		}
+	}
+}
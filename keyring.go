@@ -0,0 +1,566 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 加密密码库相关代码
+// ---------------------------------------------------------
+
+const prefKeyKeyring = "passwordKeyring"
+
+// 密码库以 base64 保存在偏好设置中, 格式为
+// 版本 (1 字节) | PBKDF2 迭代次数 (4 字节) | salt | nonce | AES-256-GCM 加密的条目 JSON
+// 版本, 迭代次数和 salt 作为附加数据参与认证
+const (
+	keyringVersion    = 1
+	keyringSaltSize   = 16
+	keyringKeySize    = 32
+	keyringHeaderSize = 1 + 4 + keyringSaltSize
+)
+
+var errKeyringMaster = errors.New("主密码错误, 或密码库已损坏")
+
+// keyringEntry 是密码库中的一个密码, 需要密码时按使用次数和最近使用时间排序尝试
+type keyringEntry struct {
+	Password string `json:"password"`
+	Uses     int    `json:"uses"`
+	LastUsed int64  `json:"lastUsed"` // Unix 秒
+}
+
+// keyring 是用主密码加密保存的常用密码, 解锁后密钥和条目只保存在内存中, 只在 UI 线程访问
+type keyring struct {
+	key        []byte // 由主密码派生的密钥, 锁定时为 nil
+	salt       []byte
+	iterations int
+	entries    []keyringEntry
+	declined   bool // 本次运行中用户拒绝过解锁, 需要密码时不再询问主密码
+}
+
+var passwordKeyring = &keyring{}
+
+func keyringExists() bool {
+	return fyne.CurrentApp().Preferences().String(prefKeyKeyring) != ""
+}
+
+func (k *keyring) unlocked() bool {
+	return k.key != nil
+}
+
+// newKeyring 创建空的密码库, 派生密钥较慢, 应在后台调用
+func newKeyring(master string) (keyring, error) {
+	salt := make([]byte, keyringSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return keyring{}, err
+	}
+	key, err := pbkdf2.Key(sha256.New, master, salt, KEYRING_KDF_ITERATIONS, keyringKeySize)
+	if err != nil {
+		return keyring{}, err
+	}
+	return keyring{key: key, salt: salt, iterations: KEYRING_KDF_ITERATIONS}, nil
+}
+
+// openKeyring 用主密码解密保存的密码库, 派生密钥较慢, 应在后台调用
+func openKeyring(data string, master string) (keyring, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil || len(raw) < keyringHeaderSize || raw[0] != keyringVersion {
+		return keyring{}, errKeyringMaster
+	}
+	header := raw[:keyringHeaderSize]
+	iterations := int(binary.BigEndian.Uint32(header[1:5]))
+	salt := append([]byte(nil), header[5:]...)
+	key, err := pbkdf2.Key(sha256.New, master, salt, iterations, keyringKeySize)
+	if err != nil {
+		return keyring{}, err
+	}
+	gcm, err := keyringCipher(key)
+	if err != nil {
+		return keyring{}, err
+	}
+	rest := raw[keyringHeaderSize:]
+	if len(rest) < gcm.NonceSize() {
+		return keyring{}, errKeyringMaster
+	}
+	plain, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], header)
+	if err != nil {
+		return keyring{}, errKeyringMaster
+	}
+	defer clear(plain)
+	var entries []keyringEntry
+	if err := json.Unmarshal(plain, &entries); err != nil {
+		return keyring{}, errKeyringMaster
+	}
+	return keyring{key: key, salt: salt, iterations: iterations, entries: entries}, nil
+}
+
+func keyringCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// save 用新的 nonce 重新加密全部条目并写入偏好设置
+func (k *keyring) save() error {
+	if !k.unlocked() {
+		return errors.New("密码库未解锁")
+	}
+	gcm, err := keyringCipher(k.key)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(k.entries)
+	if err != nil {
+		return err
+	}
+	defer clear(plain)
+
+	header := make([]byte, keyringHeaderSize)
+	header[0] = keyringVersion
+	binary.BigEndian.PutUint32(header[1:5], uint32(k.iterations))
+	copy(header[5:], k.salt)
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	raw := append(header, nonce...)
+	raw = gcm.Seal(raw, nonce, plain, header)
+	fyne.CurrentApp().Preferences().SetString(prefKeyKeyring, base64.StdEncoding.EncodeToString(raw))
+	return nil
+}
+
+// use 记录一次密码的使用, 密码不在库中时加入
+func (k *keyring) use(password []byte) error {
+	if len(password) == 0 {
+		return nil
+	}
+	now := time.Now().Unix()
+	found := false
+	for i := range k.entries {
+		if k.entries[i].Password == string(password) {
+			k.entries[i].Uses++
+			k.entries[i].LastUsed = now
+			found = true
+			break
+		}
+	}
+	if !found {
+		k.entries = append(k.entries, keyringEntry{Password: string(password), Uses: 1, LastUsed: now})
+	}
+	return k.save()
+}
+
+func (k *keyring) remove(i int) error {
+	k.entries = append(k.entries[:i], k.entries[i+1:]...)
+	return k.save()
+}
+
+// sorted 返回按使用次数和最近使用时间排序的条目副本
+func (k *keyring) sorted() []keyringEntry {
+	out := append([]keyringEntry(nil), k.entries...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Uses != out[j].Uses {
+			return out[i].Uses > out[j].Uses
+		}
+		return out[i].LastUsed > out[j].LastUsed
+	})
+	return out
+}
+
+// candidates 返回需要密码时自动尝试的密码的副本, 最多 KEYRING_TRY_MAX 个, 用完后由调用方清零
+func (k *keyring) candidates() [][]byte {
+	var out [][]byte
+	for _, e := range k.sorted() {
+		if len(out) == KEYRING_TRY_MAX {
+			break
+		}
+		out = append(out, []byte(e.Password))
+	}
+	return out
+}
+
+// lock 丢弃内存中的密钥和条目
+func (k *keyring) lock() {
+	clear(k.key)
+	k.key = nil
+	k.entries = nil
+}
+
+// destroy 删除保存的密码库
+func (k *keyring) destroy() {
+	k.lock()
+	*k = keyring{}
+	fyne.CurrentApp().Preferences().RemoveValue(prefKeyKeyring)
+}
+
+// keyringPending 是输入时勾选了"保存到密码库"的密码的副本, 确认可以打开对应的压缩包后 (见 rememberPassword) 才存入密码库
+var keyringPending = make(map[string][]byte)
+
+// verifyPassword 判断密码能否打开压缩包: 测试最小的加密文件, 只尝试列表时可能误判
+// items 为 nil 时先用该密码列出文件, 文件名加密的压缩包列不出时即说明密码错误
+// 没有可以单独校验的加密文件时测试整个压缩包
+func verifyPassword(ctx context.Context, archivePath string, password []byte, codePage int, items []archiveItem) bool {
+	// 7zz 卡住时终止并视为密码不对, 之后会询问用户
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	onStall := cancelOnStall(cancel, nil)
+	if items == nil {
+		output, _, err := archiver.List(ctx, archivePath, password, codePage, onStall)
+		if err != nil {
+			return false
+		}
+		items = parse7zzListSlt(output)
+	}
+
+	// 空文件没有可以校验的数据, 任何密码都能通过测试
+	var smallest *archiveItem
+	for i := range items {
+		it := &items[i]
+		if it.isDir || !it.encrypted || it.size == 0 {
+			continue
+		}
+		if smallest == nil || it.size < smallest.size {
+			smallest = it
+		}
+	}
+	var paths []string
+	if smallest != nil {
+		paths = []string{smallest.name}
+	}
+	_, err := archiver.Test(ctx, archivePath, password, codePage, paths, nil, onStall)
+	return err == nil
+}
+
+// askPassword 在压缩包需要密码时调用, 先在后台尝试密码库中的密码, 都不对时再弹出输入框
+// 密码库已锁定时先询问主密码; items 为已知的条目, 用于挑选验证密码的文件, 不知道时传 nil
+// ctx 结束时 (例如已拖入新文件) 不再调用任何回调; onConfirm 得到的密码归它所有, 用完后清零或交给 setCurrentPassword
+func askPassword(ctx context.Context, win fyne.Window, archivePath string, codePage int, items []archiveItem, onConfirm func(pwd []byte), onCancel func()) {
+	prompt := func() { promptPassword(win, archivePath, onConfirm, onCancel) }
+	try := func() { tryKeyring(ctx, win, archivePath, codePage, items, onConfirm, prompt) }
+	switch {
+	case passwordKeyring.unlocked():
+		try()
+	case keyringExists() && !passwordKeyring.declined:
+		showKeyringUnlock(win, try, func() {
+			passwordKeyring.declined = true
+			prompt()
+		})
+	default:
+		prompt()
+	}
+}
+
+// tryKeyring 依次验证密码库中的密码, 找到时以该密码调用 onFound, 都不对或用户跳过时调用 onNone
+func tryKeyring(ctx context.Context, win fyne.Window, archivePath string, codePage int, items []archiveItem, onFound func(pwd []byte), onNone func()) {
+	candidates := passwordKeyring.candidates()
+	if len(candidates) == 0 {
+		onNone()
+		return
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(parent)
+
+	msgLabel := widget.NewLabel(fmt.Sprintf("正在尝试密码库中的 %d 个密码:\n%s", len(candidates), filepath.Base(archivePath)))
+	msgLabel.Alignment = fyne.TextAlignCenter
+	content := wrapWithMinSize(container.NewCenter(container.NewVBox(msgLabel, widget.NewProgressBarInfinite())))
+	d := dialog.NewCustom("需要密码", "跳过", content, win)
+	// done 之前关闭对话框说明用户点了跳过
+	done, skipped := false, false
+	d.SetOnClosed(func() {
+		if !done {
+			skipped = true
+			cancel()
+		}
+	})
+	d.Show()
+
+	go func() {
+		defer cancel()
+		// 找到的密码交给 onFound, 其余候选清零
+		var found []byte
+		for _, pwd := range candidates {
+			if found == nil && ctx.Err() == nil && verifyPassword(ctx, archivePath, pwd, codePage, items) {
+				found = pwd
+				continue
+			}
+			clear(pwd)
+		}
+
+		fyne.Do(func() {
+			done = true
+			d.Hide()
+			switch {
+			case found != nil && parent.Err() == nil:
+				_ = passwordKeyring.use(found)
+				rememberPassword(archivePath, found)
+				onFound(found)
+			case found != nil:
+				clear(found)
+			case skipped || parent.Err() == nil:
+				onNone()
+			}
+		})
+	}()
+}
+
+// ensureKeyring 确保密码库已解锁, 没有密码库时先创建
+func ensureKeyring(win fyne.Window, onReady func(), onCancel func()) {
+	switch {
+	case passwordKeyring.unlocked():
+		onReady()
+	case keyringExists():
+		showKeyringUnlock(win, onReady, onCancel)
+	default:
+		showKeyringCreate(win, onReady, onCancel)
+	}
+}
+
+// runKeyringKDF 在后台执行派生密钥的 open 或 create, 完成后替换当前的密码库
+func runKeyringKDF(win fyne.Window, title string, fn func() (keyring, error), onDone func(), onCancel func()) {
+	msgLabel := widget.NewLabel(title)
+	msgLabel.Alignment = fyne.TextAlignCenter
+	d := dialog.NewCustomWithoutButtons("密码库", wrapWithMinSize(container.NewCenter(container.NewVBox(msgLabel, widget.NewProgressBarInfinite()))), win)
+	d.Show()
+	go func() {
+		k, err := fn()
+		fyne.Do(func() {
+			d.Hide()
+			if err == nil {
+				passwordKeyring.lock()
+				*passwordKeyring = k
+				err = passwordKeyring.save()
+			}
+			if err != nil {
+				e := dialog.NewError(err, win)
+				e.SetOnClosed(onCancel)
+				e.Show()
+				return
+			}
+			onDone()
+		})
+	}()
+}
+
+// masterPasswordForm 返回居中的提示和输入框
+func masterPasswordForm(msg string, entries ...*widget.Entry) fyne.CanvasObject {
+	msgLabel := widget.NewLabel(msg)
+	msgLabel.Alignment = fyne.TextAlignCenter
+	vbox := container.NewVBox(msgLabel)
+	for _, e := range entries {
+		vbox.Add(container.NewCenter(container.NewGridWrap(fyne.NewSize(300, 40), e)))
+	}
+	return wrapWithMinSize(container.NewCenter(vbox))
+}
+
+func showKeyringUnlock(win fyne.Window, onUnlocked func(), onCancel func()) {
+	pwdEntry := widget.NewPasswordEntry()
+	pwdEntry.PlaceHolder = "主密码"
+	content := masterPasswordForm("请输入密码库的主密码:", pwdEntry)
+
+	d := dialog.NewCustomConfirm("解锁密码库", "解锁", "取消", content, func(ok bool) {
+		if !ok {
+			onCancel()
+			return
+		}
+		data := fyne.CurrentApp().Preferences().String(prefKeyKeyring)
+		master := pwdEntry.Text
+		runKeyringKDF(win, "正在解锁密码库...", func() (keyring, error) {
+			return openKeyring(data, master)
+		}, onUnlocked, onCancel)
+	}, win)
+	d.Show()
+	win.Canvas().Focus(pwdEntry)
+}
+
+func showKeyringCreate(win fyne.Window, onCreated func(), onCancel func()) {
+	pwdEntry := widget.NewPasswordEntry()
+	pwdEntry.PlaceHolder = "主密码"
+	confirmEntry := widget.NewPasswordEntry()
+	confirmEntry.PlaceHolder = "再次输入主密码"
+	content := masterPasswordForm("密码库用主密码加密保存常用密码.\n请设置主密码, 忘记后无法找回:", pwdEntry, confirmEntry)
+
+	d := dialog.NewCustomConfirm("创建密码库", "创建", "取消", content, func(ok bool) {
+		if !ok {
+			onCancel()
+			return
+		}
+		var err error
+		switch {
+		case pwdEntry.Text == "":
+			err = errors.New("主密码不能为空")
+		case pwdEntry.Text != confirmEntry.Text:
+			err = errors.New("两次输入的主密码不一致")
+		}
+		if err != nil {
+			e := dialog.NewError(err, win)
+			e.SetOnClosed(onCancel)
+			e.Show()
+			return
+		}
+		master := pwdEntry.Text
+		runKeyringKDF(win, "正在创建密码库...", func() (keyring, error) {
+			return newKeyring(master)
+		}, onCreated, onCancel)
+	}, win)
+	d.Show()
+	win.Canvas().Focus(pwdEntry)
+}
+
+// maskPassword 只显示密码的首尾字符
+func maskPassword(pwd string) string {
+	n := utf8.RuneCountInString(pwd)
+	if n <= 2 {
+		return strings.Repeat("*", n)
+	}
+	r := []rune(pwd)
+	return string(r[0]) + strings.Repeat("*", n-2) + string(r[n-1])
+}
+
+// showKeyringDialog 显示密码库的管理窗口: 创建, 解锁, 查看和删除密码, 锁定
+func showKeyringDialog(win fyne.Window) {
+	reopen := func() { showKeyringDialog(win) }
+	nop := func() {}
+
+	if !passwordKeyring.unlocked() {
+		msg := "密码库用主密码加密保存常用密码, 压缩包需要密码时会先自动尝试其中的密码."
+		if keyringExists() {
+			msg += "\n\n密码库已锁定."
+		}
+		msgLabel := widget.NewLabel(msg)
+		msgLabel.Wrapping = fyne.TextWrapWord
+		msgLabel.Alignment = fyne.TextAlignCenter
+		content := wrapWithMinSize(msgLabel)
+
+		if !keyringExists() {
+			dialog.ShowCustomConfirm("密码库", "创建", "关闭", content, func(ok bool) {
+				if ok {
+					showKeyringCreate(win, reopen, nop)
+				}
+			}, win)
+			return
+		}
+		var d dialog.Dialog
+		removeBtn := widget.NewButton("删除密码库", func() {
+			d.Hide()
+			confirmKeyringDestroy(win)
+		})
+		removeBtn.Importance = widget.DangerImportance
+		d = dialog.NewCustomConfirm("密码库", "解锁", "关闭", container.NewBorder(nil, container.NewCenter(removeBtn), nil, nil, content), func(ok bool) {
+			if ok {
+				showKeyringUnlock(win, reopen, nop)
+			}
+		}, win)
+		d.Show()
+		return
+	}
+
+	entries := passwordKeyring.sorted()
+	reveal := false
+	countLbl := widget.NewLabel("")
+	var list *widget.List
+	refresh := func() {
+		entries = passwordKeyring.sorted()
+		countLbl.SetText(fmt.Sprintf("共 %d 个密码, 需要密码时最多自动尝试前 %d 个", len(entries), KEYRING_TRY_MAX))
+		list.Refresh()
+	}
+	list = widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject {
+			pwdLbl := widget.NewLabel("")
+			pwdLbl.TextStyle = fyne.TextStyle{Monospace: true}
+			pwdLbl.Truncation = fyne.TextTruncateEllipsis
+			infoLbl := widget.NewLabel("")
+			delBtn := widget.NewButton("删除", nil)
+			delBtn.Importance = widget.LowImportance
+			return container.NewBorder(nil, nil, nil, container.NewHBox(infoLbl, delBtn), pwdLbl)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			row := obj.(*fyne.Container)
+			e := entries[id]
+			pwd := maskPassword(e.Password)
+			if reveal {
+				pwd = e.Password
+			}
+			row.Objects[0].(*widget.Label).SetText(pwd)
+			side := row.Objects[1].(*fyne.Container)
+			side.Objects[0].(*widget.Label).SetText(fmt.Sprintf("使用 %d 次, 最近 %s", e.Uses, time.Unix(e.LastUsed, 0).Format("2006-01-02")))
+			side.Objects[1].(*widget.Button).OnTapped = func() {
+				for i := range passwordKeyring.entries {
+					if passwordKeyring.entries[i].Password == e.Password {
+						if err := passwordKeyring.remove(i); err != nil {
+							dialog.ShowError(err, win)
+						}
+						break
+					}
+				}
+				refresh()
+			}
+		},
+	)
+
+	revealCheck := widget.NewCheck("显示密码", func(v bool) {
+		reveal = v
+		list.Refresh()
+	})
+	addEntry := widget.NewPasswordEntry()
+	addEntry.PlaceHolder = "添加密码"
+	addBtn := widget.NewButton("添加", func() {
+		if addEntry.Text == "" {
+			return
+		}
+		if err := passwordKeyring.use([]byte(addEntry.Text)); err != nil {
+			dialog.ShowError(err, win)
+		}
+		addEntry.SetText("")
+		refresh()
+	})
+	addBar := container.NewBorder(nil, nil, nil, addBtn, addEntry)
+
+	var d dialog.Dialog
+	lockBtn := widget.NewButton("锁定", func() {
+		passwordKeyring.lock()
+		d.Hide()
+	})
+	removeBtn := widget.NewButton("删除密码库", func() {
+		d.Hide()
+		confirmKeyringDestroy(win)
+	})
+	removeBtn.Importance = widget.DangerImportance
+	refresh()
+	top := container.NewBorder(nil, nil, nil, revealCheck, countLbl)
+	bottom := container.NewVBox(addBar, container.NewHBox(lockBtn, removeBtn))
+	d = dialog.NewCustom("密码库", "关闭", container.NewBorder(top, bottom, nil, nil, list), win)
+	d.Resize(fyne.NewSize(WINDOW_WIDTH*0.6, WINDOW_HEIGHT*0.6))
+	d.Show()
+}
+
+func confirmKeyringDestroy(win fyne.Window) {
+	dialog.ShowConfirm("删除密码库", "确定删除密码库及其中保存的所有密码吗? 此操作无法撤销.", func(ok bool) {
+		if ok {
+			passwordKeyring.destroy()
+			clear(keyringPending)
+		}
+	}, win)
+}
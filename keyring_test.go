@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// TestVerifyPasswordWithoutEncryptedFiles 已知的条目中没有可以单独校验的加密文件时, 应测试整个压缩包
+func TestVerifyPasswordWithoutEncryptedFiles(t *testing.T) {
+	const archivePath = "/tmp/empty.zip"
+	fake := newFakeArchiver()
+	fake.record(fakeOpTest, archivePath, fakeResponse{output: "Everything is Ok\n"})
+	fake.record(fakeOpTest, archivePath, fakeResponse{output: "ERROR: Wrong password : a.txt\n", exitCode: exitFatal})
+	useFakeArchiver(t, fake)
+	// 假后端在 ctx 结束前一直把调用记为未结束
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	items := []archiveItem{{name: "docs", isDir: true}, {name: "docs/empty.txt", encrypted: true}}
+	if !verifyPassword(ctx, archivePath, []byte("right"), 0, items) {
+		t.Error("正确的密码没有通过验证")
+	}
+	if verifyPassword(ctx, archivePath, []byte("wrong"), 0, items) {
+		t.Error("错误的密码通过了验证")
+	}
+	for i, c := range fake.history() {
+		if c.op != fakeOpTest || c.paths != nil {
+			t.Errorf("第 %d 次调用为 %s %v, want 测试整个压缩包", i+1, c.op, c.paths)
+		}
+	}
+
+	items = append(items, archiveItem{name: "docs/a.txt", size: 10, encrypted: true})
+	verifyPassword(ctx, archivePath, []byte("wrong"), 0, items)
+	calls := fake.history()
+	if got := calls[len(calls)-1].paths; !reflect.DeepEqual(got, []string{"docs/a.txt"}) {
+		t.Errorf("有加密文件时测试了 %v, want [docs/a.txt]", got)
+	}
+}
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"image/color"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -26,6 +30,7 @@ import (
 // =========================
 
 const (
+	APP_ID                    = "com.hijzy.7zgui"
 	WINDOW_TITLE              = "7zz 解压助手"
 	WINDOW_WIDTH      float32 = 920
 	WINDOW_HEIGHT     float32 = 600
@@ -51,13 +56,56 @@ const (
 	// 对话框尺寸配置
 	DIALOG_MIN_WIDTH  float32 = 300 // 统一对话框最小宽度
 	DIALOG_MIN_HEIGHT float32 = 100 // 统一对话框最小高度
+
+	// 7zz 进程看门狗配置
+	STALL_TIMEOUT_SECONDS = 30 // 7zz 超过该秒数没有任何输出时提示用户是否终止
+
+	// 选择性解压配置
+	EXTRACT_LISTFILE_THRESHOLD = 50 // 选中项超过该数量时通过 -i@listfile 传给 7zz
+
+	// 解压目标目录配置
+	RECENT_DEST_MAX = 8 // 最多记住的最近使用解压目录数量
+
+	// 批量解压配置
+	BATCH_CONCURRENCY_DEFAULT = 2 // 默认同时解压的压缩包数量
+	BATCH_CONCURRENCY_MAX     = 8 // 可选的最大同时解压数量
+
+	// 分卷压缩包配置
+	VOLUME_SCAN_MAX = 9999 // 查找分卷时检查的最大卷号
+
+	// 预览配置
+	PREVIEW_TEXT_MAX_BYTES  = 256 * 1024       // 文本预览最多读取的字节数, 超出部分不显示
+	PREVIEW_HEX_MAX_BYTES   = 4 * 1024         // 十六进制视图显示的字节数
+	PREVIEW_IMAGE_MAX_BYTES = 20 * 1024 * 1024 // 超过该大小的图片不预览
+
+	// 列表过滤配置
+	FILTER_DEBOUNCE_MS = 150 // 停止输入该毫秒数后再过滤列表
+
+	// 解压炸弹防护
+	BOMB_MAX_SIZE_GB_DEFAULT = 100              // 默认允许的最大解压后大小
+	BOMB_MAX_RATIO_DEFAULT   = 1000             // 默认允许的最大压缩比
+	BOMB_MAX_ENTRIES_DEFAULT = 500000           // 默认允许的最大文件数量
+	BOMB_WATCH_INTERVAL_MS   = 1000             // 解压时统计目标目录大小的间隔
+	BOMB_WATCH_SLACK_BYTES   = 64 * 1024 * 1024 // 实际写入允许超出声明大小的余量, 另加 1%
+
+	// 部分成功配置
+	WARNING_TAIL_LINES = 10 // 退出码 1 但没有解析出任何警告时, 显示 7zz 输出的最后几行
+
+	// 密码库配置
+	KEYRING_KDF_ITERATIONS = 600000 // 由主密码派生密钥时 PBKDF2-SHA256 的迭代次数
+	KEYRING_TRY_MAX        = 10     // 需要密码时最多自动尝试的密码库条目数量
 )
 
 var (
 	currentFile     string
-	currentPassword string
+	currentPassword []byte
+	currentCodePage int // 当前压缩包的文件名代码页, 0 表示由 7zz 决定
 	sevenZipPath    string
 	dropCounter     atomic.Uint64
+
+	// sessionCtx 绑定当前拖入的文件, 拖入新文件时取消, 旧文件的 7zz 进程随之结束
+	sessionCtx    context.Context    = context.Background()
+	sessionCancel context.CancelFunc = func() {}
 )
 
 // myTheme 实现了 fyne.Theme 接口，用于强制指定字体
@@ -96,16 +144,19 @@ func init() {
 }
 
 type archiveItem struct {
-	name     string
-	size     uint64
-	packed   uint64
-	modified string
-	attr     string
-	isDir    bool
+	name      string
+	size      uint64
+	packed    uint64
+	modified  string
+	attr      string
+	link      string // 符号链接或硬链接的目标
+	hardLink  bool   // link 为硬链接的目标, 相对于压缩包根目录而不是链接所在目录
+	isDir     bool
+	encrypted bool
 }
 
 func main() {
-	myApp := app.New()
+	myApp := app.NewWithID(APP_ID)
 	// 应用自定义主题
 	myApp.Settings().SetTheme(&myTheme{})
 
@@ -116,17 +167,39 @@ func main() {
 		myApp.SetIcon(iconRes)
 	}
 
+	ui := buildMainWindow(myApp)
+	ui.win.ShowAndRun()
+	ui.browser.closeNested()
+}
+
+// mainWindow 是主窗口中需要从外部驱动的部分, 测试时可以配合 fyne/test 直接调用
+type mainWindow struct {
+	win      fyne.Window
+	browser  *archiveBrowser
+	actions  *actionGroup
+	progress *taskProgress
+
+	// openPaths 处理拖入的路径: 打开单个压缩包, 批量解压多个压缩包, 或为其他文件创建压缩包
+	openPaths func(paths []string)
+}
+
+// buildMainWindow 创建主窗口及其中的所有控件, 不会显示窗口
+func buildMainWindow(myApp fyne.App) *mainWindow {
 	myWindow := myApp.NewWindow(WINDOW_TITLE)
 	myWindow.Resize(fyne.NewSize(WINDOW_WIDTH, WINDOW_HEIGHT))
 
 	columns := []string{"名称", "大小", "解压后", "修改时间", "类型"}
-	items := make([]archiveItem, 0, 256)
+	browser := newArchiveBrowser()
 
 	dropHint := newDropHint()
 
-	// 使用 List 替代 Table
+	// actions 统一控制底部操作按钮, 列表加载完成前或解压过程中全部禁用
+	actions := &actionGroup{}
+	var progressPanel *taskProgress
+
+	// 使用 List 替代 Table, 只显示当前目录下的条目
 	list := widget.NewList(
-		func() int { return len(items) },
+		func() int { return len(browser.rows) },
 		func() fyne.CanvasObject {
 			// 创建列表项布局
 			icon := widget.NewIcon(nil)
@@ -147,14 +220,32 @@ func main() {
 			attrLbl.Alignment = fyne.TextAlignLeading
 
 			// 自定义布局容器
-			return container.New(newFileListLayout(),
-				icon, nameLbl, sizeLbl, packedLbl, timeLbl, attrLbl)
+			row := newFileRow(container.New(newFileListLayout(),
+				icon, nameLbl, sizeLbl, packedLbl, timeLbl, attrLbl))
+			row.onTapped = browser.tapRow
+			row.onDoubleTapped = func(id widget.ListItemID) {
+				node := browser.row(id)
+				switch {
+				case node == nil:
+				case node.item.isDir:
+					browser.enter(node)
+				case hasArchiveExt(node.name) && !actions.Disabled():
+					// 压缩包中的压缩包: 解压到临时目录后直接打开
+					openNested(myWindow, dropCounter.Load(), node, browser, actions, progressPanel, clonePassword(currentPassword))
+				}
+			}
+			return row
 		},
 		func(id widget.ListItemID, obj fyne.CanvasObject) {
-			if id < 0 || id >= len(items) {
+			node := browser.row(id)
+			if node == nil {
 				return
 			}
-			c := obj.(*fyne.Container)
+			row := obj.(*fileRow)
+			row.id = id
+			checked, inherited := browser.isChecked(node)
+			row.setChecked(checked, inherited, func(v bool) { browser.toggleRow(node, v) })
+			c := row.cells
 			icon := c.Objects[0].(*widget.Icon)
 			nameLbl := c.Objects[1].(*widget.Label)
 			sizeLbl := c.Objects[2].(*widget.Label)
@@ -162,57 +253,212 @@ func main() {
 			timeLbl := c.Objects[4].(*widget.Label)
 			attrLbl := c.Objects[5].(*widget.Label)
 
-			entry := items[id]
+			entry := node.item
 
 			// 设置图标
 			if entry.isDir {
 				icon.SetResource(theme.FolderIcon())
 			} else {
-				lowerName := strings.ToLower(entry.name)
-				if strings.HasSuffix(lowerName, ".png") ||
-					strings.HasSuffix(lowerName, ".jpg") ||
-					strings.HasSuffix(lowerName, ".jpeg") ||
-					strings.HasSuffix(lowerName, ".gif") ||
-					strings.HasSuffix(lowerName, ".bmp") ||
-					strings.HasSuffix(lowerName, ".webp") {
+				if isImageName(entry.name) {
 					icon.SetResource(theme.FileImageIcon())
 				} else {
 					icon.SetResource(theme.FileIcon())
 				}
 			}
 
-			// 设置文本
+			// 设置文本, 文件夹显示其下所有文件的合计大小
+			sizeLbl.SetText(formatSize(node.totalPacked))
+			packedLbl.SetText(formatSize(node.totalSize))
 			if entry.isDir {
-				nameLbl.SetText(entry.name + "/")
-				sizeLbl.SetText("")
-				packedLbl.SetText("")
+				nameLbl.SetText(browser.displayName(node) + "/")
 				attrLbl.SetText("文件夹")
 			} else {
-				nameLbl.SetText(entry.name)
-				sizeLbl.SetText(formatSize(entry.packed))
-				packedLbl.SetText(formatSize(entry.size))
+				nameLbl.SetText(browser.displayName(node))
 				attrLbl.SetText("文件")
 			}
 			timeLbl.SetText(entry.modified)
 		},
 	)
+	browser.list = list
 
-	var extractBtn *widget.Button
-	extractBtn = widget.NewButton("解压到当前目录", func() {
+	extractBtn := widget.NewButton("解压到当前目录", func() {
 		if currentFile == "" {
 			return
 		}
 		token := dropCounter.Load()
-		startExtract(myWindow, token, currentFile, currentPassword, extractBtn)
+		req := planOutput(extractRequest{
+			archivePath: currentFile,
+			sourceDir:   browser.sourceDir(currentFile),
+			unwrapTar:   browser.unwrapTar,
+			codePage:    currentCodePage,
+			password:    clonePassword(currentPassword),
+			items:       browser.items,
+			overwrite:   loadOverwriteMode(),
+			testFirst:   fyne.CurrentApp().Preferences().Bool(prefKeyTestBeforeExtract),
+			totalSize:   totalItemSize(browser.items),
+		}, browser.sourceDir(currentFile), loadDefaultLayout())
+		startExtract(myWindow, token, req, actions, progressPanel)
 	})
 	extractBtn.Importance = widget.LowImportance
-	extractBtn.Disable()
+
+	var extractSelBtn *widget.Button
+	extractSelBtn = widget.NewButton("解压选中项", func() {
+		paths := browser.selectedPaths()
+		if currentFile == "" || len(paths) == 0 {
+			return
+		}
+		token := dropCounter.Load()
+		req := planOutput(extractRequest{
+			archivePath: currentFile,
+			sourceDir:   browser.sourceDir(currentFile),
+			unwrapTar:   browser.unwrapTar,
+			codePage:    currentCodePage,
+			password:    clonePassword(currentPassword),
+			items:       browser.items,
+			paths:       paths,
+			overwrite:   loadOverwriteMode(),
+			testFirst:   fyne.CurrentApp().Preferences().Bool(prefKeyTestBeforeExtract),
+			totalSize:   browser.selectedSize(),
+		}, browser.sourceDir(currentFile), loadDefaultLayout())
+		startExtract(myWindow, token, req, actions, progressPanel)
+	})
+	extractSelBtn.Importance = widget.LowImportance
+	preview := newPreviewPane()
+	previewCheck := widget.NewCheck("预览", nil)
+	updatePreview := func() {
+		if !previewCheck.Checked {
+			return
+		}
+		// 只预览单独选中的一个文件
+		nodes := browser.selectedNodes()
+		if len(nodes) == 1 && !nodes[0].item.isDir {
+			preview.show(nodes[0], currentFile, currentPassword, currentCodePage, browser.unwrapTar)
+		} else if preview.node != nil {
+			preview.clear()
+		}
+	}
+	browser.onSelectionChanged = func() {
+		updatePreview()
+		n := len(browser.selectedNodes())
+		if n == 0 {
+			extractSelBtn.SetText("解压选中项")
+			extractSelBtn.Disable()
+			return
+		}
+		extractSelBtn.SetText(fmt.Sprintf("解压选中项 (%d)", n))
+		if !actions.Disabled() {
+			extractSelBtn.Enable()
+		}
+	}
+
+	extractToBtn := widget.NewButton("解压到...", func() {
+		if currentFile == "" {
+			return
+		}
+		token := dropCounter.Load()
+		base := extractRequest{
+			archivePath: currentFile,
+			unwrapTar:   browser.unwrapTar,
+			codePage:    currentCodePage,
+			sourceDir:   browser.sourceDir(currentFile),
+			items:       browser.items,
+			overwrite:   loadOverwriteMode(),
+			testFirst:   fyne.CurrentApp().Preferences().Bool(prefKeyTestBeforeExtract),
+			totalSize:   totalItemSize(browser.items),
+		}
+		showExtractToDialog(myWindow, base, func(req extractRequest) {
+			if token != dropCounter.Load() {
+				return
+			}
+			req.password = clonePassword(currentPassword)
+			startExtract(myWindow, token, req, actions, progressPanel)
+		})
+	})
+	extractToBtn.Importance = widget.LowImportance
+
+	testBtn := widget.NewButton("测试", func() {
+		if currentFile == "" {
+			return
+		}
+		token := dropCounter.Load()
+		archivePath := currentFile
+		actions.Disable()
+		runArchiveTest(myWindow, token, archivePath, clonePassword(currentPassword), currentCodePage, nil, totalItemSize(browser.items), progressPanel, func(report testReport) {
+			showTestReport(myWindow, archivePath, report, nil, actions.Enable)
+		}, actions.Enable)
+	})
+	testBtn.Importance = widget.LowImportance
+
+	actions.buttons = []fyne.Disableable{extractBtn, extractSelBtn, extractToBtn, testBtn}
+	actions.onEnable = browser.onSelectionChanged
+	actions.Disable()
+
 	extractBtnBg := canvas.NewRectangle(parseHexColor(HEADER_BG_COLOR))
-	extractBar := container.NewStack(extractBtnBg, extractBtn)
+	overwriteSel := newOverwriteSelect()
+	smartCheck := widget.NewCheck("智能解压", func(v bool) {
+		fyne.CurrentApp().Preferences().SetBool(prefKeySmartExtract, v)
+	})
+	smartCheck.SetChecked(loadDefaultLayout() == layoutSmart)
+	testFirstCheck := widget.NewCheck("解压前测试", func(v bool) {
+		fyne.CurrentApp().Preferences().SetBool(prefKeyTestBeforeExtract, v)
+	})
+	testFirstCheck.SetChecked(fyne.CurrentApp().Preferences().Bool(prefKeyTestBeforeExtract))
+	limitsBtn := widget.NewButton("限制", func() { showBombLimitsDialog(myWindow) })
+	limitsBtn.Importance = widget.LowImportance
+	overwriteBox := container.NewHBox(limitsBtn, testFirstCheck, smartCheck, widget.NewLabel("同名文件:"), overwriteSel)
+	extractBar := container.NewStack(extractBtnBg, container.NewBorder(nil, nil, nil, overwriteBox,
+		container.NewGridWithColumns(4, extractBtn, extractSelBtn, extractToBtn, testBtn)))
+	progressPanel = newTaskProgress(extractBar)
+	bottomBar := container.NewStack(extractBar, progressPanel.container)
+
+	// 创建自定义表头, 表头和列表一起放在预览区左侧以保持列对齐
+	header := createListHeader(columns, browser)
+	listPane := container.NewBorder(container.NewVBox(newFilterBar(browser), header), nil, nil, nil, list)
+	listSplit := container.NewHSplit(listPane, preview.container)
+	listSplit.SetOffset(0.65)
+	// 关闭预览时只显示列表, 不保留分隔条和预览区的空白
+	listArea := container.NewStack()
+	showPreviewPane := func(v bool) {
+		if v {
+			listSplit.Leading = listPane
+			listArea.Objects = []fyne.CanvasObject{listSplit}
+			listSplit.Refresh()
+		} else {
+			listArea.Objects = []fyne.CanvasObject{listPane}
+		}
+		listArea.Refresh()
+	}
+
+	previewCheck.OnChanged = func(v bool) {
+		fyne.CurrentApp().Preferences().SetBool(prefKeyShowPreview, v)
+		showPreviewPane(v)
+		if v {
+			updatePreview()
+		} else {
+			preview.clear()
+		}
+	}
+	previewCheck.SetChecked(fyne.CurrentApp().Preferences().Bool(prefKeyShowPreview))
+	if !previewCheck.Checked {
+		showPreviewPane(false)
+	}
 
-	// 创建自定义表头
-	header := createListHeader(columns)
-	listPage := container.NewBorder(header, extractBar, nil, nil, list)
+	// 切换文件名编码后用新的编码重新读取列表
+	codePageSelect := newCodePageSelect(func(cp int) {
+		currentCodePage = cp
+		if currentFile == "" {
+			return
+		}
+		token := newSession()
+		browser.clear()
+		progressPanel.stop()
+		actions.Disable()
+		startListFiles(myWindow, token, currentFile, clonePassword(currentPassword), browser, actions)
+	})
+	keyringBtn := widget.NewButton("密码库", func() { showKeyringDialog(myWindow) })
+	keyringBtn.Importance = widget.LowImportance
+	topRight := container.NewHBox(keyringBtn, widget.NewLabel("文件名编码:"), codePageSelect, previewCheck)
+	listPage := container.NewBorder(container.NewBorder(nil, nil, nil, topRight, browser.bar), bottomBar, nil, nil, listArea)
 	listPage.Hide()
 
 	contentStack := container.NewStack(dropHint, listPage)
@@ -224,75 +470,228 @@ func main() {
 
 	myWindow.SetContent(mainContainer)
 
-	myWindow.SetOnDropped(func(pos fyne.Position, uris []fyne.URI) {
-		if len(uris) == 0 {
-			return
-		}
-
-		filePath := uris[0].Path()
-		if filePath == "" {
+	openPaths := func(paths []string) {
+		if len(paths) == 0 {
 			return
 		}
-		filePath = filepath.Clean(filePath)
+		filePath := paths[0]
 
 		info, err := os.Stat(filePath)
 		if err != nil {
 			dialog.ShowError(fmt.Errorf("无法读取文件: %s", err.Error()), myWindow)
 			return
 		}
-		if info.IsDir() {
-			dialog.ShowInformation("提示", "请拖入单个压缩文件, 不要拖入文件夹", myWindow)
+		// 同一组分卷只保留入口卷
+		if allArchives(paths) {
+			paths = uniqueArchives(paths)
+		}
+		// 拖入多个压缩包时加入批量队列, 拖入文件夹或多个普通文件时创建压缩包
+		if len(paths) > 1 && allArchives(paths) {
+			showBatchQueue(paths)
+			return
+		}
+		if info.IsDir() || len(paths) > 1 {
+			showCreateArchiveDialog(myWindow, paths)
 			return
 		}
+		filePath = paths[0]
 
-		token := dropCounter.Add(1)
+		if vol, ok := detectVolumeSet(filePath); ok {
+			if _, err := os.Stat(vol.first); err != nil {
+				dialog.ShowError(fmt.Errorf("找不到分卷压缩包的第一卷:\n%s", vol.first), myWindow)
+				return
+			}
+			if missing := vol.missingText(); missing != "" {
+				msgLabel := widget.NewLabel("以下分卷缺失, 解压可能失败:\n" + missing)
+				msgLabel.Wrapping = fyne.TextWrapWord
+				dialog.ShowCustom("分卷不完整", "确定", container.NewVScroll(wrapWithMinSize(msgLabel)), myWindow)
+			}
+		}
+
+		token := newSession()
 		currentFile = filePath
-		currentPassword = ""
+		// 本次运行中打开过的压缩包不再询问密码
+		setCurrentPassword(cachedPassword(filePath))
+		setCodePage(0)
 
-		items = items[:0]
-		list.Refresh()
-		extractBtn.Disable()
+		browser.closeNested()
+		browser.clear()
+		progressPanel.stop()
+		actions.Disable()
 
 		dropHint.Hide()
 		listPage.Show()
-		startListFiles(myWindow, token, filePath, "", &items, list, extractBtn)
+		startListFiles(myWindow, token, filePath, clonePassword(currentPassword), browser, actions)
+	}
+
+	myWindow.SetOnDropped(func(pos fyne.Position, uris []fyne.URI) {
+		paths := make([]string, 0, len(uris))
+		for _, u := range uris {
+			if p := u.Path(); p != "" {
+				paths = append(paths, filepath.Clean(p))
+			}
+		}
+		openPaths(paths)
 	})
 
-	myWindow.ShowAndRun()
+	// 返回外层压缩包时删除内层的临时文件
+	browser.onLeaveLevel = func(i int) {
+		newSession()
+		lv := browser.popTo(i)
+		currentFile = lv.path
+		setCurrentPassword(lv.password)
+		setCodePage(lv.codePage)
+		progressPanel.stop()
+		actions.Enable()
+	}
+
+	return &mainWindow{
+		win:       myWindow,
+		browser:   browser,
+		actions:   actions,
+		progress:  progressPanel,
+		openPaths: openPaths,
+	}
+}
+
+// newSession 取消上一个文件的所有 7zz 进程, 并返回新文件的 token
+func newSession() uint64 {
+	sessionCancel()
+	sessionCtx, sessionCancel = context.WithCancel(context.Background())
+	return dropCounter.Add(1)
+}
+
+// newStallPrompt 返回一个看门狗回调, 7zz 长时间没有输出时询问用户是否终止
+// 7zz 恢复输出或结束时关闭仍在显示的询问框; current 返回 false 时说明该任务已不再相关, 不再打扰用户
+func newStallPrompt(win fyne.Window, cancel context.CancelFunc, current func() bool) func(stalled bool) {
+	// d 为正在显示的询问框, 只在 UI 线程访问
+	var d dialog.Dialog
+	return func(stalled bool) {
+		fyne.Do(func() {
+			if !stalled {
+				if d != nil {
+					d.Hide()
+					d = nil
+				}
+				return
+			}
+			if !current() || d != nil {
+				return
+			}
+			msg := fmt.Sprintf("7zz 已超过 %d 秒没有任何输出, 可能已卡住(例如在等待交互输入).\n是否终止该进程?", STALL_TIMEOUT_SECONDS)
+			msgLabel := widget.NewLabel(msg)
+			msgLabel.Wrapping = fyne.TextWrapWord
+			msgLabel.Alignment = fyne.TextAlignCenter
+			d = dialog.NewCustomConfirm("进程无响应", "终止", "继续等待", wrapWithMinSize(msgLabel), func(ok bool) {
+				d = nil
+				if ok {
+					cancel()
+				}
+			}, win)
+			d.Show()
+		})
+	}
+}
+
+// cancelOnStall 返回用于后台任务的看门狗回调, 这些任务没有可以询问的界面, 7zz 长时间没有输出时直接终止
+// stalled 不为 nil 时记录是否因此终止, 以便与用户取消区分
+func cancelOnStall(cancel context.CancelFunc, stalled *atomic.Bool) func(stalled bool) {
+	return func(s bool) {
+		if !s {
+			return
+		}
+		if stalled != nil {
+			stalled.Store(true)
+		}
+		cancel()
+	}
 }
 
-func startListFiles(win fyne.Window, token uint64, archivePath string, password string, items *[]archiveItem, list *widget.List, btn *widget.Button) {
+// startListFiles 在后台列出压缩包内容, password 归本次列出所有, 结束时清零或交给 currentPassword
+func startListFiles(win fyne.Window, token uint64, archivePath string, password []byte, browser *archiveBrowser, btn fyne.Disableable) {
+	// 全局状态只在 UI 线程读写, 先取出编码再交给后台
+	cp := currentCodePage
+	ctx, cancel := context.WithCancel(sessionCtx)
 	go func() {
-		output, err := run7zzList(archivePath, password)
+		defer cancel()
+		output, unwrapTar, err := archiver.List(ctx, archivePath, password, cp, newStallPrompt(win, cancel, func() bool { return token == dropCounter.Load() }))
+		canceled := ctx.Err() != nil
 
 		fyne.Do(func() {
+			keep := false
+			defer func() {
+				if !keep {
+					clear(password)
+				}
+			}()
 			if token != dropCounter.Load() || archivePath != currentFile {
 				return
 			}
 
+			if canceled {
+				dialog.ShowInformation("已终止", "已终止读取文件列表", win)
+				return
+			}
+
 			if err != nil && is7zzNotFound(err) {
 				dialog.ShowError(fmt.Errorf("找不到 7zz.\n请把 7zz 文件和本程序放在同一个文件夹.\n当前尝试路径: %s", sevenZipPath), win)
 				return
 			}
 
-			if needsPassword(output) {
-				showPasswordDialog(win, token, archivePath, items, list, btn)
+			if needsPassword(output, err) {
+				encryptedListings[archivePath] = true
+				showPasswordDialog(win, token, archivePath, browser, btn)
 				return
 			}
 
 			if err != nil {
-				dialog.ShowError(fmt.Errorf("%s", output), win)
+				show7zzError(win, "无法读取文件列表", output, err)
 				return
 			}
 
+			// 文件名已加密的压缩包只有密码正确才能列出, 其余压缩包用任何密码都能列出, 不能据此确认密码
+			if encryptedListings[archivePath] {
+				rememberPassword(archivePath, password)
+				setCurrentPassword(password)
+				keep = true
+			}
 			parsed := parse7zzListSlt(output)
-			*items = append((*items)[:0], parsed...)
-			list.Refresh()
+			browser.unwrapTar = unwrapTar
+			browser.setItems(filepath.Base(archivePath), parsed)
 			btn.Enable()
+			if currentCodePage == 0 {
+				suggestCodePage(win, token, archivePath, password, output, parsed)
+			}
 		})
 	}()
 }
 
+// actionGroup 把多个按钮当作一个整体启用或禁用
+type actionGroup struct {
+	buttons  []fyne.Disableable
+	disabled bool
+	onEnable func() // 启用后调用, 用于让依赖其他状态的按钮重新判断
+}
+
+func (g *actionGroup) Enable() {
+	g.disabled = false
+	for _, b := range g.buttons {
+		b.Enable()
+	}
+	if g.onEnable != nil {
+		g.onEnable()
+	}
+}
+
+func (g *actionGroup) Disable() {
+	g.disabled = true
+	for _, b := range g.buttons {
+		b.Disable()
+	}
+}
+
+func (g *actionGroup) Disabled() bool { return g.disabled }
+
 // 包装内容以确保最小尺寸
 func wrapWithMinSize(content fyne.CanvasObject) fyne.CanvasObject {
 	// 使用透明矩形撑开尺寸
@@ -304,12 +703,16 @@ func wrapWithMinSize(content fyne.CanvasObject) fyne.CanvasObject {
 	return container.NewStack(spacer, content)
 }
 
-func showPasswordDialog(win fyne.Window, token uint64, archivePath string, items *[]archiveItem, list *widget.List, btn *widget.Button) {
+// promptPassword 弹出密码输入框, 确认时以输入的密码调用 onConfirm, 取消时调用 onCancel
+// onConfirm 得到的密码归它所有, 用完后清零或交给 setCurrentPassword
+// 一般通过 askPassword 调用, 勾选"保存到密码库"时先解锁或创建密码库, 密码确认可用后存入
+func promptPassword(win fyne.Window, archivePath string, onConfirm func(pwd []byte), onCancel func()) {
 	pwdEntry := widget.NewPasswordEntry()
 	pwdEntry.PlaceHolder = "请输入密码"
 
 	// 限制输入框宽度
 	entryWrapper := container.NewGridWrap(fyne.NewSize(300, 40), pwdEntry)
+	saveCheck := widget.NewCheck("保存到密码库", nil)
 
 	// 提示信息
 	fileName := filepath.Base(archivePath)
@@ -317,35 +720,46 @@ func showPasswordDialog(win fyne.Window, token uint64, archivePath string, items
 	msgLabel := widget.NewLabel(msg)
 	msgLabel.Alignment = fyne.TextAlignCenter
 
-	// 组合内容：垂直排列 文本 + 输入框
-	// 使用 Center 布局让输入框居中显示
-	vbox := container.NewVBox(msgLabel, container.NewCenter(entryWrapper))
-
-	// 居中整个内容块在对话框中
+	vbox := container.NewVBox(msgLabel, container.NewCenter(entryWrapper), container.NewCenter(saveCheck))
 	centeredContent := container.NewCenter(vbox)
-
-	// 强制最小尺寸
 	content := wrapWithMinSize(centeredContent)
 
 	d := dialog.NewCustomConfirm("需要密码", "确定", "取消", content, func(ok bool) {
 		if !ok {
+			onCancel()
 			return
 		}
-
-		currentPassword := pwdEntry.Text
-		btn.Disable()
-		*items = (*items)[:0]
-		list.Refresh()
-		startListFiles(win, token, archivePath, currentPassword, items, list, btn)
+		// 输入框中的文本是无法清零的字符串, 取出后立即清空输入框, 之后只使用字节切片
+		pwd := []byte(pwdEntry.Text)
+		pwdEntry.SetText("")
+		if !saveCheck.Checked || len(pwd) == 0 {
+			onConfirm(pwd)
+			return
+		}
+		// 无法解锁密码库时仍然使用输入的密码, 只是不保存
+		ensureKeyring(win, func() {
+			clear(keyringPending[archivePath])
+			keyringPending[archivePath] = clonePassword(pwd)
+			onConfirm(pwd)
+		}, func() { onConfirm(pwd) })
 	}, win)
-
-	// 显示对话框
 	d.Show()
-
-	// 尝试聚焦输入框 (Fyne 的聚焦有时候需要延时或在 Show 之后)
 	win.Canvas().Focus(pwdEntry)
 }
 
+// showPasswordDialog 在读取文件列表需要密码时询问, 用得到的密码重新读取
+func showPasswordDialog(win fyne.Window, token uint64, archivePath string, browser *archiveBrowser, btn fyne.Disableable) {
+	askPassword(sessionCtx, win, archivePath, currentCodePage, nil, func(pwd []byte) {
+		if token != dropCounter.Load() {
+			clear(pwd)
+			return
+		}
+		btn.Disable()
+		browser.clear()
+		startListFiles(win, token, archivePath, pwd, browser, btn)
+	}, func() {})
+}
+
 // ---------------------------------------------------------
 // 自定义布局相关代码
 // ---------------------------------------------------------
@@ -433,33 +847,47 @@ func (l *fileListLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
 	return fyne.NewSize(COL_WIDTH_SIZE+COL_WIDTH_PACKED+COL_WIDTH_TIME+COL_WIDTH_TYPE+100, h)
 }
 
-func createListHeader(columns []string) fyne.CanvasObject {
-	// 创建表头标签
-	nameLbl := widget.NewLabel(columns[0])
-	nameLbl.TextStyle = fyne.TextStyle{Bold: true}
-
-	sizeLbl := widget.NewLabel(columns[1])
-	sizeLbl.TextStyle = fyne.TextStyle{Bold: true}
-	sizeLbl.Alignment = fyne.TextAlignLeading
-
-	packedLbl := widget.NewLabel(columns[2])
-	packedLbl.TextStyle = fyne.TextStyle{Bold: true}
-	packedLbl.Alignment = fyne.TextAlignLeading
-
-	timeLbl := widget.NewLabel(columns[3])
-	timeLbl.TextStyle = fyne.TextStyle{Bold: true}
-	timeLbl.Alignment = fyne.TextAlignLeading
-
-	attrLbl := widget.NewLabel(columns[4])
-	attrLbl.TextStyle = fyne.TextStyle{Bold: true}
-	attrLbl.Alignment = fyne.TextAlignLeading
+func createListHeader(columns []string, browser *archiveBrowser) fyne.CanvasObject {
+	// 创建表头标签, 点击切换排序: 首次点击升序, 再次点击同一列改为降序
+	browser.sortCol, browser.sortDesc = loadSort()
+	cells := make([]*headerCell, len(columns))
+	updateLabels := func() {
+		for i, c := range cells {
+			text := columns[i]
+			if i == browser.sortCol {
+				if browser.sortDesc {
+					text += " ▼"
+				} else {
+					text += " ▲"
+				}
+			}
+			c.SetText(text)
+		}
+	}
+	for i := range columns {
+		col := i
+		cells[i] = newHeaderCell(columns[i], func() {
+			desc := false
+			if browser.sortCol == col {
+				desc = !browser.sortDesc
+			}
+			saveSort(col, desc)
+			browser.setSort(col, desc)
+			updateLabels()
+		})
+	}
+	updateLabels()
 
 	// 使用相同的布局，但第一个元素放一个空的占位符代替图标
 	spacer := canvas.NewRectangle(color.Transparent)
 
 	// 使用自定义布局容器
 	c := container.New(newFileListLayout(),
-		spacer, nameLbl, sizeLbl, packedLbl, timeLbl, attrLbl)
+		spacer, cells[0], cells[1], cells[2], cells[3], cells[4])
+
+	// 列表每行左侧有勾选框, 表头留出相同宽度保持列对齐
+	checkSpacer := canvas.NewRectangle(color.Transparent)
+	checkSpacer.SetMinSize(fyne.NewSize(checkboxWidth(), 0))
 
 	// 添加背景和分割线
 	// 使用自定义颜色作为表头背景，确保与列表内容区分明显
@@ -468,69 +896,279 @@ func createListHeader(columns []string) fyne.CanvasObject {
 	line.SetMinSize(fyne.NewSize(0, 1))
 
 	return container.NewBorder(nil, line, nil, nil,
-		container.NewStack(bg, c))
+		container.NewStack(bg, container.NewBorder(nil, nil, checkSpacer, nil, c)))
 }
 
-func startExtract(win fyne.Window, token uint64, archivePath string, password string, btn *widget.Button) {
-	btn.Disable()
-	outputDir := defaultOutputDir(archivePath)
-	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+// extractRequest 描述一次解压操作
+type extractRequest struct {
+	archivePath   string
+	sourceDir     string // 默认的解压位置, 嵌套打开时为最外层压缩包所在的目录
+	password      []byte
+	items         []archiveItem // 压缩包的完整列表, 用于解压前的检查
+	paths         []string      // 压缩包内路径, 为空时解压全部内容
+	excludes      []string      // 需要跳过的压缩包内路径
+	outputDir     string
+	overwrite     overwriteMode
+	totalSize     uint64 // 待解压内容的解压后总大小, 用于估算速度和剩余时间
+	testFirst     bool   // 解压前先运行 7zz t
+	unwrapTar     bool   // 压缩包是单个 tar 的压缩流, 通过管道直接展开 tar
+	codePage      int    // 文件名代码页, 0 表示由 7zz 决定
+	safeChecked   bool   // 已完成路径安全检查
+	limitsChecked bool   // 已完成解压炸弹检查
+	spaceChecked  bool   // 已完成剩余空间检查
+
+	// 智能解压: smartTop 为唯一的顶层文件夹, 与已有路径重名时先解压到 stageDir 再改名为 smartTarget
+	smartTop    string
+	smartTarget string
+	stageDir    string
+}
+
+// startExtract 检查并解压 req, req.password 归本次解压所有, 结束或中途放弃时清零, 成功时交给 currentPassword
+func startExtract(win fyne.Window, token uint64, req extractRequest, btn fyne.Disableable, panel *taskProgress) {
+	abort := func() {
+		clear(req.password)
 		btn.Enable()
+	}
+
+	// 先测试压缩包, 未通过时由用户决定是否继续
+	if req.testFirst {
+		btn.Disable()
+		// 压缩的 tar 包只能整体测试外层的压缩流
+		testPaths := req.paths
+		if req.unwrapTar {
+			testPaths = nil
+		}
+		runArchiveTest(win, token, req.archivePath, clonePassword(req.password), req.codePage, testPaths, req.totalSize, panel, func(report testReport) {
+			showTestReport(win, req.archivePath, report, func() {
+				// 测试时可能输入了新密码, 见 runArchiveTest
+				req.testFirst = false
+				clear(req.password)
+				req.password = clonePassword(currentPassword)
+				startExtract(win, token, req, btn, panel)
+			}, abort)
+		}, abort)
+		return
+	}
+
+	// 检查有风险的路径, 有问题时由用户决定跳过, 清理还是中止
+	if !req.safeChecked {
+		req.safeChecked = true
+		if entries := findUnsafeEntries(req); len(entries) > 0 {
+			btn.Disable()
+			showSafetyDialog(win, entries, func() {
+				if token != dropCounter.Load() {
+					return
+				}
+				req.excludes = append(req.excludes, unsafeNames(entries)...)
+				startExtract(win, token, req, btn, panel)
+			}, func() {
+				if token != dropCounter.Load() {
+					return
+				}
+				req.sanitize(entries)
+				startExtract(win, token, req, btn, panel)
+			}, abort)
+			return
+		}
+	}
+
+	// 检查解压后大小, 压缩比和文件数量
+	if !req.limitsChecked {
+		req.limitsChecked = true
+		limits := loadBombLimits()
+		if problems := checkBombLimits(req, limits); len(problems) > 0 {
+			btn.Disable()
+			showBombWarning(win, problems, limits.block, func() {
+				if token != dropCounter.Load() {
+					return
+				}
+				startExtract(win, token, req, btn, panel)
+			}, abort)
+			return
+		}
+	}
+
+	// 检查目标位置的剩余空间, 不足时可以换一个位置
+	if !req.spaceChecked {
+		req.spaceChecked = true
+		if shortage, short := checkFreeSpace(req); short {
+			btn.Disable()
+			showSpaceDialog(win, shortage, func() {
+				// 选择位置的对话框可能被直接关闭, 先放弃本次解压, 确认新位置时再取当前密码
+				abort()
+				showExtractToDialog(win, req, func(r extractRequest) {
+					if token != dropCounter.Load() {
+						return
+					}
+					r.spaceChecked = false
+					r.password = clonePassword(currentPassword)
+					startExtract(win, token, r, btn, panel)
+				})
+			}, func() {
+				if token != dropCounter.Load() {
+					return
+				}
+				startExtract(win, token, req, btn, panel)
+			}, abort)
+			return
+		}
+	}
+
+	// 询问模式: 先比对磁盘, 有冲突时由用户决定具体的覆盖方式
+	if req.overwrite == overwriteAsk {
+		req.overwrite = overwriteAll
+		if conflicts := findConflicts(req); len(conflicts) > 0 {
+			btn.Disable()
+			showConflictDialog(win, conflicts, func(mode overwriteMode, excludes []string) {
+				if token != dropCounter.Load() {
+					return
+				}
+				req.overwrite = mode
+				req.excludes = append(req.excludes, excludes...)
+				startExtract(win, token, req, btn, panel)
+			}, abort)
+			return
+		}
+	}
+
+	btn.Disable()
+	archivePath := req.archivePath
+	if err := os.MkdirAll(req.outputDir, 0o755); err != nil {
+		abort()
 		dialog.ShowError(fmt.Errorf("无法创建目录: %s", err.Error()), win)
 		return
 	}
+	if err := req.prepareStage(); err != nil {
+		abort()
+		dialog.ShowError(fmt.Errorf("无法创建临时目录: %s", err.Error()), win)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(sessionCtx)
+	panel.start(req.totalSize, cancel)
+	watch := watchOutputSize(ctx, req, loadBombLimits(), cancel)
 
 	go func() {
-		output, err := run7zzExtract(archivePath, outputDir, password)
+		defer cancel()
+
+		// lastFile 记录 7zz 最近报告的正在处理的条目, 取消时它可能只写了一半
+		lastFile := ""
+		lastPercent := -1
+		output, err := archiver.Extract(ctx, req, func(info progressInfo) {
+			if info.percent == lastPercent && (info.file == "" || info.file == lastFile) {
+				return
+			}
+			lastPercent = info.percent
+			if info.file != "" {
+				lastFile = info.file
+			}
+			fyne.Do(func() {
+				if token != dropCounter.Load() || archivePath != currentFile {
+					return
+				}
+				panel.update(info)
+			})
+		}, newStallPrompt(win, cancel, func() bool { return token == dropCounter.Load() }))
+		canceled := ctx.Err() != nil
 
 		fyne.Do(func() {
+			keep := false
+			defer func() {
+				if !keep {
+					clear(req.password)
+				}
+			}()
+			// 需要重新输入密码时丢弃临时目录, 重试时重新创建; 其余情况把内容移到最终位置
+			// 已切换到其他文件时也要处理, 不能把 .7zgui-* 临时目录留在用户的文件夹中
+			var stageErr error
+			if !canceled && needsPassword(output, err) {
+				if req.stageDir != "" {
+					_ = os.RemoveAll(req.stageDir)
+					req.stageDir = ""
+				}
+			} else {
+				stageErr = req.finishStage()
+			}
 			if token != dropCounter.Load() || archivePath != currentFile {
 				return
 			}
+			panel.stop()
 
-			if err != nil && is7zzNotFound(err) {
-				dialog.ShowError(fmt.Errorf("找不到 7zz.\n请把 7zz 文件和本程序放在同一个文件夹.\n当前尝试路径: %s", sevenZipPath), win)
+			if stageErr != nil {
+				dialog.ShowError(fmt.Errorf("无法移动到 %s: %s\n文件保留在: %s", req.smartTarget, stageErr.Error(), req.stageDir), win)
+				btn.Enable()
 				return
 			}
 
-			if needsPassword(output) {
-				pwdEntry := widget.NewPasswordEntry()
-				pwdEntry.PlaceHolder = "请输入密码"
-
-				// 限制输入框宽度
-				entryWrapper := container.NewGridWrap(fyne.NewSize(300, 40), pwdEntry)
+			if watch.exceeded.Load() {
+				dialog.ShowError(fmt.Errorf("%s", watch.message(req.resultDir())), win)
+				btn.Enable()
+				return
+			}
 
-				// 提示信息
-				fileName := filepath.Base(archivePath)
-				msg := fmt.Sprintf("请输入压缩包密码:\n%s", fileName)
+			if canceled {
+				msg := "解压已取消, 已解压的文件保留在:\n" + req.resultDir()
+				if lastFile != "" {
+					msg += "\n\n以下文件可能未完整写入:\n" + req.diskPath(lastFile)
+				}
 				msgLabel := widget.NewLabel(msg)
+				msgLabel.Wrapping = fyne.TextWrapWord
 				msgLabel.Alignment = fyne.TextAlignCenter
+				dialog.ShowCustom("已取消", "确定", wrapWithMinSize(msgLabel), win)
+				btn.Enable()
+				return
+			}
 
-				vbox := container.NewVBox(msgLabel, container.NewCenter(entryWrapper))
-				centeredContent := container.NewCenter(vbox)
-				content := wrapWithMinSize(centeredContent)
+			if err != nil && is7zzNotFound(err) {
+				dialog.ShowError(fmt.Errorf("找不到 7zz.\n请把 7zz 文件和本程序放在同一个文件夹.\n当前尝试路径: %s", sevenZipPath), win)
+				btn.Enable()
+				return
+			}
 
-				d := dialog.NewCustomConfirm("需要密码", "确定", "取消", content, func(ok bool) {
-					if !ok {
-						btn.Enable()
+			if needsPassword(output, err) {
+				// 解压成功后才把新密码用于之后的操作, 见下方 rememberPassword
+				askPassword(sessionCtx, win, archivePath, req.codePage, req.items, func(pwd []byte) {
+					if token != dropCounter.Load() {
+						clear(pwd)
 						return
 					}
-					currentPassword = pwdEntry.Text
-					startExtract(win, token, archivePath, currentPassword, btn)
-				}, win)
-				d.Show()
-				win.Canvas().Focus(pwdEntry)
+					req.password = pwd
+					startExtract(win, token, req, btn, panel)
+				}, btn.Enable)
+				return
+			}
+
+			// 只有部分条目出错时列出这些条目, 其余文件已正常解压
+			if warnings := parse7zzExtractWarnings(output, req); isPartialSuccess(err, warnings) {
+				// 重试沿用本次的密码, 不重试时再清零
+				keep = true
+				var onRetry func()
+				if retry, ok := req.retryFailed(warnings); ok {
+					onRetry = func() {
+						if token != dropCounter.Load() {
+							return
+						}
+						startExtract(win, token, retry, btn, panel)
+					}
+				}
+				showExtractWarnings(win, req.resultDir(), warnings, output, onRetry, abort)
 				return
 			}
 
 			if err != nil {
-				dialog.ShowError(fmt.Errorf("解压失败: %s", output), win)
+				show7zzError(win, "解压失败", output, err)
 				btn.Enable()
 				return
 			}
 
+			if len(req.password) > 0 {
+				rememberPassword(archivePath, req.password)
+				setCurrentPassword(req.password)
+				keep = true
+			}
+
 			// 解压成功，显示统一大小的对话框
-			msgLabel := widget.NewLabel("文件已解压到:\n" + outputDir)
+			msgLabel := widget.NewLabel("文件已解压到:\n" + req.resultDir())
 			msgLabel.Wrapping = fyne.TextWrapWord
 			msgLabel.Alignment = fyne.TextAlignCenter
 
@@ -545,61 +1183,235 @@ func startExtract(win fyne.Window, token uint64, archivePath string, password st
 	}()
 }
 
-func run7zzList(archivePath string, password string) (string, error) {
-	args := []string{"l", "-slt", archivePath}
-	if password != "" {
-		args = append(args, "-p"+password)
-	} else {
-		args = append(args, "-p")
+func run7zzList(ctx context.Context, archivePath string, password []byte, codePage int, onStall func(stalled bool)) (string, error) {
+	args := append([]string{"l", "-slt", archivePath}, codePageArgs(codePage)...)
+	return run7zz(ctx, onStall, password, args...)
+}
+
+func run7zzExtract(ctx context.Context, req extractRequest, onProgress func(progressInfo), onStall func(stalled bool)) (string, error) {
+	args := []string{"x", req.archivePath, "-y", req.overwrite.switchArg(), "-bsp1", "-bso1", "-o" + req.extractDir()}
+	var producer []string
+	if req.unwrapTar {
+		// 外层 7zz 只负责解压缩并报告进度, 第二个 7zz 从标准输入展开 tar, 不会留下中间的 .tar
+		producer = unwrapTarArgs(req.archivePath)
+		args = []string{"x", "-si", "-ttar", "-y", req.overwrite.switchArg(), "-bsp0", "-bso1", "-o" + req.extractDir()}
+	}
+	args = append(args, codePageArgs(req.codePage)...)
+	// 跳过的路径取自列表, 区分大小写匹配, 以免在 Windows 上把只有大小写不同的另一个条目一起跳过
+	if len(req.excludes) > 0 {
+		args = append(args, "-ssc")
 	}
-	return run7zz(args...)
+
+	// 选中或跳过的文件较多时写入列表文件, 避免命令行过长
+	args, cleanup, err := appendSelectionArgs(args, req.paths, req.excludes)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	onLine := func(line string) {
+		if info, ok := parse7zzProgress(line); ok && onProgress != nil {
+			onProgress(info)
+		}
+	}
+	if producer != nil {
+		return run7zzPipeline(ctx, onLine, onStall, req.password, producer, args)
+	}
+	return run7zzStream(ctx, onLine, onStall, req.password, args...)
 }
 
-func run7zzExtract(archivePath string, outputDir string, password string) (string, error) {
-	args := []string{"x", archivePath, "-y", "-o" + outputDir}
-	if password != "" {
-		args = append(args, "-p"+password)
-	} else {
-		args = append(args, "-p")
+// writeListFile 把路径逐行写入临时文件, 供 7zz 的 -i@listfile 使用
+func writeListFile(paths []string) (string, error) {
+	f, err := os.CreateTemp("", "7zgui-list-*.txt")
+	if err != nil {
+		return "", err
 	}
-	return run7zz(args...)
+	for _, p := range paths {
+		if _, err := f.WriteString(p + "\n"); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return "", err
+		}
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
 }
 
-func run7zz(args ...string) (string, error) {
-	cmd := exec.Command(sevenZipPath, args...)
-	var buf bytes.Buffer
-	cmd.Stdout = &buf
-	cmd.Stderr = &buf
-	err := cmd.Run()
-	return buf.String(), err
+func run7zz(ctx context.Context, onStall func(stalled bool), password []byte, args ...string) (string, error) {
+	return run7zzStream(ctx, nil, onStall, password, args...)
 }
 
-func is7zzNotFound(err error) bool {
-	var execErr *exec.Error
-	if errors.As(err, &execErr) && errors.Is(execErr.Err, exec.ErrNotFound) {
-		return true
+// run7zzStream 运行 7zz 并在输出到达时逐行回调 onLine
+// 返回的输出中不包含进度行, 方便直接展示给用户
+// 超过 STALL_TIMEOUT_SECONDS 没有输出时调用 onStall(true), 恢复输出或结束时调用 onStall(false), 之后可以再次触发
+// password 通过标准输入交给 7zz, 见 passwordStdin
+func run7zzStream(ctx context.Context, onLine func(string), onStall func(stalled bool), password []byte, args ...string) (string, error) {
+	stdin, send, err := passwordStdin(password)
+	if err != nil {
+		return "", err
 	}
-	return false
+	cmd := exec.CommandContext(ctx, sevenZipPath, args...)
+	out := newOutputCollector(onLine, onStall)
+	cmd.Stdin = stdin
+	cmd.Stdout = out
+	cmd.Stderr = out
+	err = cmd.Start()
+	send()
+	if err != nil {
+		out.finish()
+		return "", err
+	}
+
+	err = cmd.Wait()
+	return out.finish(), err
 }
 
-func needsPassword(output string) bool {
-	s := strings.ToLower(output)
-	// 7zz 提示输入密码的常见文本
-	if strings.Contains(s, "enter password") {
-		return true
+// run7zzPipeline 运行两个 7zz 进程, producer 的标准输出接到 consumer 的标准输入
+// 两个进程的其余输出都交给同一个 outputCollector, password 通过标准输入交给 producer
+func run7zzPipeline(ctx context.Context, onLine func(string), onStall func(stalled bool), password []byte, producer []string, consumer []string) (string, error) {
+	prod := exec.CommandContext(ctx, sevenZipPath, producer...)
+	cons := exec.CommandContext(ctx, sevenZipPath, consumer...)
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", err
 	}
-	// 密码错误提示
-	if strings.Contains(s, "wrong password") {
-		return true
+	stdin, send, err := passwordStdin(password)
+	if err != nil {
+		r.Close()
+		w.Close()
+		return "", err
 	}
-	// 某些情况下的加密提示
-	if strings.Contains(s, "encrypted") && strings.Contains(s, "password") {
-		return true
+	out := newOutputCollector(onLine, onStall)
+	prod.Stdin = stdin
+	prod.Stdout = w
+	prod.Stderr = out
+	cons.Stdin = r
+	cons.Stdout = out
+	cons.Stderr = out
+
+	if err := cons.Start(); err != nil {
+		send()
+		r.Close()
+		w.Close()
+		out.finish()
+		return "", err
+	}
+	err = prod.Start()
+	send()
+	if err != nil {
+		r.Close()
+		w.Close()
+		_ = cons.Process.Kill()
+		_ = cons.Wait()
+		out.finish()
+		return "", err
+	}
+	// 子进程已各自持有管道, 父进程必须关闭自己的副本, consumer 才能读到 EOF
+	r.Close()
+	w.Close()
+
+	prodErr := prod.Wait()
+	consErr := cons.Wait()
+	output := out.finish()
+	if prodErr != nil {
+		return output, prodErr
+	}
+	return output, consErr
+}
+
+// outputCollector 逐行读取 7zz 的输出, 同时负责看门狗计时
+type outputCollector struct {
+	pr         *io.PipeReader
+	pw         *io.PipeWriter
+	buf        bytes.Buffer
+	done       chan struct{}
+	lastOutput atomic.Int64
+}
+
+func newOutputCollector(onLine func(string), onStall func(stalled bool)) *outputCollector {
+	c := &outputCollector{done: make(chan struct{})}
+	c.pr, c.pw = io.Pipe()
+	c.lastOutput.Store(time.Now().UnixNano())
+
+	go func() {
+		defer close(c.done)
+		sc := bufio.NewScanner(c.pr)
+		sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		sc.Split(split7zzOutput)
+		for sc.Scan() {
+			c.lastOutput.Store(time.Now().UnixNano())
+			line := stripPasswordPrompt(sc.Text())
+			if onLine != nil {
+				onLine(line)
+			}
+			if _, ok := parse7zzProgress(line); ok || strings.TrimSpace(line) == "" {
+				continue
+			}
+			c.buf.WriteString(line)
+			c.buf.WriteByte('\n')
+		}
+		// 扫描出错时继续读完, 避免子进程阻塞在写管道上
+		_, _ = io.Copy(io.Discard, c.pr)
+	}()
+
+	if onStall != nil {
+		go watch7zzOutput(c.done, &c.lastOutput, onStall)
+	}
+	return c
+}
+
+// Write 可以被多个进程的输出并发调用, io.Pipe 会把它们串行化
+func (c *outputCollector) Write(p []byte) (int, error) {
+	return c.pw.Write(p)
+}
+
+// finish 在所有进程结束后调用, 返回不含进度行的完整输出
+func (c *outputCollector) finish() string {
+	_ = c.pw.Close()
+	<-c.done
+	return c.buf.String()
+}
+
+// watch7zzOutput 是 7zz 进程的看门狗, done 关闭后退出
+// 超时后调用 onStall(true), 之后恢复输出或进程结束时调用 onStall(false)
+func watch7zzOutput(done <-chan struct{}, lastOutput *atomic.Int64, onStall func(stalled bool)) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	timeout := time.Duration(STALL_TIMEOUT_SECONDS) * time.Second
+	flagged := false
+	for {
+		select {
+		case <-done:
+			if flagged {
+				onStall(false)
+			}
+			return
+		case <-ticker.C:
+			idle := time.Since(time.Unix(0, lastOutput.Load()))
+			if idle < timeout {
+				if flagged {
+					flagged = false
+					onStall(false)
+				}
+				continue
+			}
+			if !flagged {
+				flagged = true
+				onStall(true)
+			}
+		}
 	}
-	// 无法打开文件作为归档，有时也是因为加密头导致无法识别
-	// 但这可能也会误判损坏的文件，暂不启用
-	// if strings.Contains(s, "cannot open the file as archive") { ... }
+}
 
+func is7zzNotFound(err error) bool {
+	var execErr *exec.Error
+	if errors.As(err, &execErr) && errors.Is(execErr.Err, exec.ErrNotFound) {
+		return true
+	}
 	return false
 }
 
@@ -612,6 +1424,12 @@ func detectArchiveSuffix(path string) string {
 		return ".tar.bz2"
 	case strings.HasSuffix(name, ".tar.xz"):
 		return ".tar.xz"
+	case strings.HasSuffix(name, ".tar.zst"):
+		return ".tar.zst"
+	case strings.HasSuffix(name, ".tar.lz4"):
+		return ".tar.lz4"
+	case strings.HasSuffix(name, ".tar.lzma"):
+		return ".tar.lzma"
 	case strings.HasSuffix(name, ".tgz"):
 		return ".tgz"
 	case strings.HasSuffix(name, ".tbz2"):
@@ -662,7 +1480,19 @@ func resolve7zzPath() string {
 
 func defaultOutputDir(archivePath string) string {
 	parent := filepath.Dir(archivePath)
-	base := filepath.Base(archivePath)
+	name := stripArchiveSuffix(filepath.Base(archivePath))
+	// 分卷压缩包使用整组的基础名称, 避免出现 demo.7z 这样的目录
+	if v, ok := detectVolumeSet(archivePath); ok {
+		name = v.base
+	}
+	if name == "" {
+		name = "output"
+	}
+	return filepath.Join(parent, name)
+}
+
+// stripArchiveSuffix 去掉文件名中的压缩包后缀, 例如 demo.tar.gz -> demo
+func stripArchiveSuffix(base string) string {
 	suffix := detectArchiveSuffix(base)
 	name := base
 	if suffix != "-" && strings.HasSuffix(strings.ToLower(name), suffix) {
@@ -673,10 +1503,7 @@ func defaultOutputDir(archivePath string) string {
 			name = name[:len(name)-len(ext)]
 		}
 	}
-	if name == "" {
-		name = "output"
-	}
-	return filepath.Join(parent, name)
+	return name
 }
 
 func parse7zzListSlt(output string) []archiveItem {
@@ -758,6 +1585,17 @@ func parse7zzListSlt(output string) []archiveItem {
 				continue
 			}
 			cur.attr = val
+		case "Encrypted":
+			if !hasCur {
+				continue
+			}
+			cur.encrypted = val == "+"
+		case "Symbolic Link", "Hard Link":
+			if !hasCur {
+				continue
+			}
+			cur.link = val
+			cur.hardLink = key == "Hard Link"
 		}
 	}
 	flush()
@@ -772,6 +1610,14 @@ func parse7zzListSlt(output string) []archiveItem {
 	return out
 }
 
+func totalItemSize(items []archiveItem) uint64 {
+	var total uint64
+	for _, it := range items {
+		total += it.size
+	}
+	return total
+}
+
 func formatSize(v uint64) string {
 	if v == 0 {
 		return "0.00MB"
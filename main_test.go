@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+)
+
+// encryptedListing 是 7zz l -slt 列出一个文件名未加密的小压缩包的输出
+const encryptedListing = `7-Zip (z) 24.08 (x64) : Copyright (c) 1999-2024 Igor Pavlov : 2024-08-11
+
+Listing archive: secret.7z
+
+--
+Path = secret.7z
+Type = 7z
+Physical Size = 420
+Headers Size = 180
+Method = LZMA2:12 7zAES
+Solid = +
+Blocks = 1
+
+----------
+Path = docs
+Folder = +
+Size = 0
+Packed Size = 0
+Modified = 2024-05-01 10:20:30.1234567
+Attributes = D
+CRC = 
+Encrypted = -
+Method = 
+Block = 
+
+Path = docs/readme.txt
+Size = 1200
+Packed Size = 240
+Modified = 2024-05-01 10:20:31.5000000
+Attributes = A
+CRC = 1A2B3C4D
+Encrypted = +
+Method = LZMA2:12 7zAES
+Block = 0
+
+Path = docs/link
+Size = 9
+Packed Size = 0
+Modified = 2024-05-01 10:20:32
+Attributes = A
+Encrypted = +
+Symbolic Link = readme.txt
+`
+
+func TestParse7zzListSlt(t *testing.T) {
+	got := parse7zzListSlt(encryptedListing)
+	want := []archiveItem{
+		{name: "docs", isDir: true, modified: "2024-05-01 10:20:30", attr: "D"},
+		{name: "docs/readme.txt", size: 1200, packed: 240, modified: "2024-05-01 10:20:31", attr: "A", encrypted: true},
+		{name: "docs/link", size: 9, modified: "2024-05-01 10:20:32", attr: "A", encrypted: true, link: "readme.txt"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parse7zzListSlt =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+// useFakeArchiver 在测试期间把后端替换为 f
+func useFakeArchiver(t *testing.T, f *fakeArchiver) {
+	old := archiver
+	archiver = f
+	t.Cleanup(func() { archiver = old })
+}
+
+// findInOverlay 在最上层的对话框中查找第一个满足 match 的对象
+func findInOverlay(win fyne.Window, match func(fyne.CanvasObject) bool) fyne.CanvasObject {
+	top := win.Canvas().Overlays().Top()
+	if top == nil {
+		return nil
+	}
+	var walk func(o fyne.CanvasObject) fyne.CanvasObject
+	walk = func(o fyne.CanvasObject) fyne.CanvasObject {
+		if match(o) {
+			return o
+		}
+		var children []fyne.CanvasObject
+		switch c := o.(type) {
+		case *fyne.Container:
+			children = c.Objects
+		case fyne.Widget:
+			children = test.WidgetRenderer(c).Objects()
+		}
+		for _, child := range children {
+			if found := walk(child); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return walk(top)
+}
+
+func overlayButton(win fyne.Window, text string) *widget.Button {
+	o := findInOverlay(win, func(o fyne.CanvasObject) bool {
+		b, ok := o.(*widget.Button)
+		return ok && b.Text == text
+	})
+	b, _ := o.(*widget.Button)
+	return b
+}
+
+func overlayLabel(win fyne.Window, text string) *widget.Label {
+	o := findInOverlay(win, func(o fyne.CanvasObject) bool {
+		l, ok := o.(*widget.Label)
+		return ok && l.Text == text
+	})
+	l, _ := o.(*widget.Label)
+	return l
+}
+
+// TestOpenEncryptedArchiveAndExtract 打开文件名已加密的压缩包, 输入密码后解压, 解压时不应再次询问密码
+func TestOpenEncryptedArchiveAndExtract(t *testing.T) {
+	test.NewApp()
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "secret.7z")
+	if err := os.WriteFile(archivePath, []byte("7z"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := newFakeArchiver()
+	fake.record(fakeOpList, archivePath, fakeResponse{
+		output:   "ERROR: " + archivePath + "\nCan not open encrypted archive. Wrong password?\n",
+		exitCode: exitFatal,
+	})
+	fake.record(fakeOpList, archivePath, fakeResponse{output: encryptedListing})
+	fake.record(fakeOpExtract, archivePath, fakeResponse{
+		output: " 40% 1 - docs/readme.txt\n100% 3\nEverything is Ok\n",
+	})
+	useFakeArchiver(t, fake)
+
+	wantOps := []string{fakeOpList, fakeOpList, fakeOpExtract}
+	wantPasswords := []string{"", "hunter2", "hunter2"}
+
+	ui := buildMainWindow(fyne.CurrentApp())
+	ui.win.Resize(fyne.NewSize(WINDOW_WIDTH, WINDOW_HEIGHT))
+	ui.openPaths([]string{archivePath})
+
+	fake.waitCalls(t, 1)
+	confirm := overlayButton(ui.win, "确定")
+	if confirm == nil {
+		t.Fatal("文件名已加密时没有询问密码")
+	}
+	entry, _ := findInOverlay(ui.win, func(o fyne.CanvasObject) bool {
+		e, ok := o.(*widget.Entry)
+		return ok && e.Password
+	}).(*widget.Entry)
+	if entry == nil {
+		t.Fatal("密码对话框中没有密码输入框")
+	}
+	test.Type(entry, "hunter2")
+	test.Tap(confirm)
+
+	fake.waitCalls(t, 2)
+	if len(ui.browser.items) != 3 || ui.actions.Disabled() {
+		t.Fatalf("输入密码后没有列出文件, items = %d", len(ui.browser.items))
+	}
+
+	extractBtn := ui.actions.buttons[0].(*widget.Button)
+	test.Tap(extractBtn)
+
+	fake.waitCalls(t, 3)
+	if overlayLabel(ui.win, "文件已解压到:\n"+filepath.Join(dir, "secret")) == nil {
+		t.Fatal("没有显示解压完成")
+	}
+
+	calls := fake.history()
+	if len(calls) != len(wantOps) {
+		t.Fatalf("收到 %d 次调用, want %d", len(calls), len(wantOps))
+	}
+	for i, c := range calls {
+		if c.op != wantOps[i] || c.password != wantPasswords[i] {
+			t.Errorf("第 %d 次调用为 %s 密码 %q, want %s 密码 %q", i+1, c.op, c.password, wantOps[i], wantPasswords[i])
+		}
+	}
+	if got := cachedPassword(archivePath); string(got) != "hunter2" {
+		t.Errorf("cachedPassword = %q, want hunter2", got)
+	}
+}
+
+func TestExtractWithout7zzEnablesButton(t *testing.T) {
+	test.NewApp()
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "plain.7z")
+	if err := os.WriteFile(archivePath, []byte("7z"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := newFakeArchiver()
+	fake.record(fakeOpList, archivePath, fakeResponse{output: encryptedListing})
+	fake.record(fakeOpExtract, archivePath, fakeResponse{exitCode: fakeNotFound})
+	useFakeArchiver(t, fake)
+
+	ui := buildMainWindow(fyne.CurrentApp())
+	ui.win.Resize(fyne.NewSize(WINDOW_WIDTH, WINDOW_HEIGHT))
+	ui.openPaths([]string{archivePath})
+	fake.waitCalls(t, 1)
+
+	extractBtn := ui.actions.buttons[0].(*widget.Button)
+	test.Tap(extractBtn)
+	fake.waitCalls(t, 2)
+
+	if overlayLabel(ui.win, "找不到 7zz.\n请把 7zz 文件和本程序放在同一个文件夹.\n当前尝试路径: "+sevenZipPath) == nil {
+		t.Error("没有提示找不到 7zz")
+	}
+	if extractBtn.Disabled() {
+		t.Error("找不到 7zz 后解压按钮仍被禁用")
+	}
+}
+
+func TestCancelOnStall(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var stalled atomic.Bool
+	onStall := cancelOnStall(cancel, &stalled)
+
+	onStall(false)
+	if ctx.Err() != nil || stalled.Load() {
+		t.Fatal("7zz 恢复输出时不应终止")
+	}
+	onStall(true)
+	if ctx.Err() == nil || !stalled.Load() {
+		t.Fatal("7zz 卡住时没有终止")
+	}
+
+	// 不关心原因时 stalled 可以为 nil
+	ctx, cancel = context.WithCancel(context.Background())
+	cancelOnStall(cancel, nil)(true)
+	if ctx.Err() == nil {
+		t.Fatal("stalled 为 nil 时没有终止")
+	}
+}
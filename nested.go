@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+// ---------------------------------------------------------
+// 嵌套压缩包相关代码
+// ---------------------------------------------------------
+
+// archiveLevel 记录进入内层压缩包前外层压缩包的状态, 返回该层时据此恢复
+type archiveLevel struct {
+	path      string // 磁盘上的压缩包路径, 内层压缩包为临时文件
+	name      string // 面包屑中显示的名称
+	password  []byte // 外层压缩包的密码, 归该层所有, 离开或丢弃该层时清零
+	items     []archiveItem
+	unwrapTar bool
+	codePage  int
+	dir       string // 离开时所在的目录, 为压缩包内路径
+	tempDir   string // 该层压缩包所在的临时目录, 最外层为空
+}
+
+// pushLevel 保存当前压缩包的浏览状态, 并清空列表准备显示内层压缩包 name, password 交给该层保存
+func (b *archiveBrowser) pushLevel(path string, password []byte, codePage int, name string, tempDir string) {
+	lv := archiveLevel{
+		path:      path,
+		name:      b.rootName,
+		password:  password,
+		items:     append([]archiveItem(nil), b.items...),
+		unwrapTar: b.unwrapTar,
+		codePage:  codePage,
+		tempDir:   b.tempDir,
+	}
+	if b.current != nil {
+		lv.dir = b.current.item.name
+	}
+	b.levels = append(b.levels, lv)
+	b.tempDir = tempDir
+	b.rootName = name
+	b.clear()
+}
+
+// popTo 返回第 i 层压缩包, 删除更内层的临时文件并清零它们的密码, 返回该层的状态, 其中的密码交给调用方
+func (b *archiveBrowser) popTo(i int) archiveLevel {
+	lv := b.levels[i]
+	_ = os.RemoveAll(b.tempDir)
+	for _, inner := range b.levels[i+1:] {
+		_ = os.RemoveAll(inner.tempDir)
+		clear(inner.password)
+	}
+	b.levels = b.levels[:i]
+	b.tempDir = lv.tempDir
+	b.unwrapTar = lv.unwrapTar
+
+	b.setItems(lv.name, lv.items)
+	if n := b.findDir(lv.dir); n != nil {
+		b.enter(n)
+	}
+	return lv
+}
+
+// closeNested 删除所有内层压缩包的临时文件并清零各层的密码, 用于拖入新文件或退出程序
+func (b *archiveBrowser) closeNested() {
+	_ = os.RemoveAll(b.tempDir)
+	for _, lv := range b.levels {
+		_ = os.RemoveAll(lv.tempDir)
+		clear(lv.password)
+	}
+	b.levels = nil
+	b.tempDir = ""
+}
+
+// sourceDir 返回默认的解压位置, 嵌套打开时为最外层压缩包所在的目录
+func (b *archiveBrowser) sourceDir(archivePath string) string {
+	if len(b.levels) > 0 {
+		return filepath.Dir(b.levels[0].path)
+	}
+	return filepath.Dir(archivePath)
+}
+
+// findDir 按压缩包内路径查找目录节点, 找不到时返回 nil
+func (b *archiveBrowser) findDir(p string) *treeNode {
+	n := b.root
+	for _, part := range splitArchivePath(p) {
+		if n == nil {
+			return nil
+		}
+		n = n.childIdx[part]
+	}
+	return n
+}
+
+// openNested 把压缩包内的压缩包解压到临时目录, 成功后进入该压缩包浏览
+// password 为外层压缩包的密码, 归本次打开所有, 成功后保存在该层的 archiveLevel 中, 否则清零
+func openNested(win fyne.Window, token uint64, node *treeNode, browser *archiveBrowser, actions *actionGroup, panel *taskProgress, password []byte) {
+	tempDir, err := os.MkdirTemp("", "7zgui-nested-")
+	if err != nil {
+		clear(password)
+		dialog.ShowError(fmt.Errorf("无法创建临时目录: %s", err.Error()), win)
+		return
+	}
+	archivePath := currentFile
+	unwrapTar := browser.unwrapTar
+	codePage := currentCodePage
+
+	actions.Disable()
+	ctx, cancel := context.WithCancel(sessionCtx)
+	panel.start(node.item.size, cancel)
+
+	go func() {
+		defer cancel()
+
+		lastPercent := -1
+		dst, output, err := archiver.ExtractFile(ctx, archivePath, password, codePage, unwrapTar, node.item.name, tempDir, func(info progressInfo) {
+			if info.percent == lastPercent {
+				return
+			}
+			lastPercent = info.percent
+			fyne.Do(func() {
+				if token != dropCounter.Load() || archivePath != currentFile {
+					return
+				}
+				panel.update(info)
+			})
+		}, newStallPrompt(win, cancel, func() bool { return token == dropCounter.Load() }))
+		canceled := ctx.Err() != nil
+
+		fyne.Do(func() {
+			keep := false
+			defer func() {
+				if !keep {
+					clear(password)
+				}
+			}()
+			stale := token != dropCounter.Load() || archivePath != currentFile
+			if stale || canceled || err != nil {
+				_ = os.RemoveAll(tempDir)
+			}
+			if stale {
+				return
+			}
+			panel.stop()
+
+			switch {
+			case canceled:
+				actions.Enable()
+			case err != nil && is7zzNotFound(err):
+				dialog.ShowError(fmt.Errorf("找不到 7zz.\n请把 7zz 文件和本程序放在同一个文件夹.\n当前尝试路径: %s", sevenZipPath), win)
+				actions.Enable()
+			case needsPassword(output, err):
+				askPassword(sessionCtx, win, archivePath, codePage, browser.items, func(pwd []byte) {
+					if token != dropCounter.Load() {
+						clear(pwd)
+						return
+					}
+					openNested(win, token, node, browser, actions, panel, pwd)
+				}, actions.Enable)
+			case err != nil:
+				show7zzError(win, "无法打开 "+node.name, output, err)
+				actions.Enable()
+			default:
+				rememberPassword(archivePath, password)
+				browser.pushLevel(archivePath, password, codePage, node.name, tempDir)
+				keep = true
+				currentFile = dst
+				setCurrentPassword(cachedPassword(dst))
+				setCodePage(0)
+				startListFiles(win, newSession(), dst, clonePassword(currentPassword), browser, actions)
+			}
+		})
+	}()
+}
+
+// run7zzExtractFile 把压缩包内的单个文件解压到 dir, 返回解压后的文件路径
+// 普通压缩包用 7zz e -so 直接写文件, 压缩的 tar 包先解压缩再从标准输入展开该条目
+func run7zzExtractFile(ctx context.Context, archivePath string, password []byte, codePage int, unwrapTar bool, name string, dir string, onProgress func(progressInfo), onStall func(stalled bool)) (string, string, error) {
+	onLine := func(line string) {
+		if info, ok := parse7zzProgress(line); ok && onProgress != nil {
+			onProgress(info)
+		}
+	}
+
+	if unwrapTar {
+		args := append([]string{"x", "-si", "-ttar", "-y", "-bsp0", "-bso1", "-o" + dir}, codePageArgs(codePage)...)
+		args, cleanup, err := appendPathArgs(args, []string{name})
+		if err != nil {
+			return "", "", err
+		}
+		defer cleanup()
+		output, err := run7zzPipeline(ctx, onLine, onStall, password, unwrapTarArgs(archivePath), args)
+		return filepath.Join(dir, filepath.FromSlash(name)), output, err
+	}
+
+	dst := filepath.Join(dir, filepath.Base(filepath.FromSlash(name)))
+	f, err := os.Create(dst)
+	if err != nil {
+		return "", "", err
+	}
+
+	args := append([]string{"e", archivePath, "-so", "-bsp2"}, codePageArgs(codePage)...)
+	args, cleanup, err := appendPathArgs(args, []string{name})
+	if err != nil {
+		f.Close()
+		return "", "", err
+	}
+	defer cleanup()
+
+	stdin, send, err := passwordStdin(password)
+	if err != nil {
+		f.Close()
+		return "", "", err
+	}
+	cmd := exec.CommandContext(ctx, sevenZipPath, args...)
+	out := newOutputCollector(onLine, onStall)
+	cmd.Stdin = stdin
+	cmd.Stdout = f
+	cmd.Stderr = out
+	err = cmd.Start()
+	send()
+	if err != nil {
+		f.Close()
+		out.finish()
+		return "", "", err
+	}
+
+	err = cmd.Wait()
+	closeErr := f.Close()
+	output := out.finish()
+	if err == nil {
+		err = closeErr
+	}
+	return dst, output, err
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 覆盖策略与冲突处理相关代码
+// ---------------------------------------------------------
+
+// overwriteMode 决定目标位置已存在同名文件时的处理方式
+type overwriteMode int
+
+const (
+	overwriteAsk            overwriteMode = iota // 解压前比对磁盘, 有冲突时询问
+	overwriteAll                                 // 覆盖, 对应 -aoa
+	overwriteSkip                                // 跳过, 对应 -aos
+	overwriteRenameNew                           // 重命名解压出的文件, 对应 -aou
+	overwriteRenameExisting                      // 重命名已有文件, 对应 -aot
+)
+
+const prefKeyOverwriteMode = "overwriteMode"
+
+var overwriteModeLabels = []string{"询问", "覆盖", "跳过", "重命名新文件", "重命名已有文件"}
+
+func (m overwriteMode) String() string {
+	if m < 0 || int(m) >= len(overwriteModeLabels) {
+		return overwriteModeLabels[overwriteAsk]
+	}
+	return overwriteModeLabels[m]
+}
+
+// switchArg 返回对应的 7zz 参数, 询问模式在解压前已被解析为具体模式, 这里按覆盖处理
+func (m overwriteMode) switchArg() string {
+	switch m {
+	case overwriteSkip:
+		return "-aos"
+	case overwriteRenameNew:
+		return "-aou"
+	case overwriteRenameExisting:
+		return "-aot"
+	}
+	return "-aoa"
+}
+
+func loadOverwriteMode() overwriteMode {
+	m := overwriteMode(fyne.CurrentApp().Preferences().IntWithFallback(prefKeyOverwriteMode, int(overwriteAsk)))
+	if m < 0 || int(m) >= len(overwriteModeLabels) {
+		return overwriteAsk
+	}
+	return m
+}
+
+// newOverwriteSelect 创建覆盖策略下拉框, 选择结果会被记住
+func newOverwriteSelect() *widget.Select {
+	sel := widget.NewSelect(overwriteModeLabels, func(s string) {
+		for i, label := range overwriteModeLabels {
+			if label == s {
+				fyne.CurrentApp().Preferences().SetInt(prefKeyOverwriteMode, i)
+				return
+			}
+		}
+	})
+	sel.SetSelected(loadOverwriteMode().String())
+	return sel
+}
+
+// fileConflict 描述一个压缩包条目与磁盘上已有文件的冲突
+type fileConflict struct {
+	item     archiveItem
+	diskPath string
+	diskSize int64
+	diskTime time.Time
+}
+
+// parseModified 解析 7zz 列出的修改时间, 7zz 使用本地时区
+func parseModified(s string) (time.Time, bool) {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// isUnderPaths 判断压缩包内路径是否等于 paths 中某一项或位于其下, paths 为空表示全部
+func isUnderPaths(name string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	name = strings.Join(splitArchivePath(name), "/")
+	for _, p := range paths {
+		p = strings.Join(splitArchivePath(p), "/")
+		if name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// findConflicts 找出将被解压的文件中, 目标位置已存在的文件
+func findConflicts(req extractRequest) []fileConflict {
+	var out []fileConflict
+	for _, it := range req.items {
+		if it.isDir || !isUnderPaths(it.name, req.paths) || (len(req.excludes) > 0 && isUnderPaths(it.name, req.excludes)) {
+			continue
+		}
+		diskPath := req.diskPath(it.name)
+		info, err := os.Stat(diskPath)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		out = append(out, fileConflict{
+			item:     it,
+			diskPath: diskPath,
+			diskSize: info.Size(),
+			diskTime: info.ModTime(),
+		})
+	}
+	return out
+}
+
+// describe 返回冲突双方的修改时间与大小对比
+func (c fileConflict) describe() string {
+	newer := ""
+	if t, ok := parseModified(c.item.modified); ok {
+		switch {
+		case t.After(c.diskTime.Truncate(time.Second)):
+			newer = "压缩包中的较新"
+		case t.Before(c.diskTime.Truncate(time.Second)):
+			newer = "磁盘上的较新"
+		default:
+			newer = "修改时间相同"
+		}
+	}
+	return fmt.Sprintf("压缩包: %s  %s    磁盘: %s  %s    %s",
+		c.item.modified, formatSize(c.item.size),
+		c.diskTime.Format("2006-01-02 15:04:05"), formatSize(uint64(c.diskSize)),
+		newer)
+}
+
+// showConflictDialog 列出所有冲突, 用户可以逐个勾选是否覆盖, 也可以对全部冲突应用同一种策略
+// onResolve 的 excludes 为需要跳过的压缩包内路径
+func showConflictDialog(win fyne.Window, conflicts []fileConflict, onResolve func(mode overwriteMode, excludes []string), onCancel func()) {
+	overwrite := make([]bool, len(conflicts))
+
+	list := widget.NewList(
+		func() int { return len(conflicts) },
+		func() fyne.CanvasObject {
+			nameLbl := widget.NewLabel("")
+			nameLbl.TextStyle = fyne.TextStyle{Bold: true}
+			nameLbl.Truncation = fyne.TextTruncateEllipsis
+			infoLbl := widget.NewLabel("")
+			infoLbl.Truncation = fyne.TextTruncateEllipsis
+			check := widget.NewCheck("覆盖", nil)
+			return container.NewBorder(nil, nil, nil, check, container.NewVBox(nameLbl, infoLbl))
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			c := obj.(*fyne.Container)
+			box := c.Objects[0].(*fyne.Container)
+			check := c.Objects[1].(*widget.Check)
+			box.Objects[0].(*widget.Label).SetText(conflicts[id].item.name)
+			box.Objects[1].(*widget.Label).SetText(conflicts[id].describe())
+			check.OnChanged = nil
+			check.SetChecked(overwrite[id])
+			check.OnChanged = func(v bool) { overwrite[id] = v }
+		},
+	)
+
+	msg := widget.NewLabel(fmt.Sprintf("目标位置已存在 %d 个同名文件, 请选择处理方式:", len(conflicts)))
+	msg.Wrapping = fyne.TextWrapWord
+
+	var d dialog.Dialog
+	finish := func(mode overwriteMode, excludes []string) {
+		d.Hide()
+		onResolve(mode, excludes)
+	}
+
+	applyAll := func(label string, mode overwriteMode) *widget.Button {
+		return widget.NewButton(label, func() { finish(mode, nil) })
+	}
+	byCheckBtn := widget.NewButton("按勾选执行", func() {
+		var excludes []string
+		for i, c := range conflicts {
+			if !overwrite[i] {
+				excludes = append(excludes, c.item.name)
+			}
+		}
+		finish(overwriteAll, excludes)
+	})
+	byCheckBtn.Importance = widget.HighImportance
+	cancelBtn := widget.NewButton("取消", func() {
+		d.Hide()
+		onCancel()
+	})
+
+	buttons := container.NewGridWithColumns(3,
+		applyAll("全部覆盖", overwriteAll),
+		applyAll("全部跳过", overwriteSkip),
+		applyAll("全部重命名新文件", overwriteRenameNew),
+		applyAll("全部重命名已有文件", overwriteRenameExisting),
+		byCheckBtn,
+		cancelBtn,
+	)
+
+	content := container.NewBorder(msg, buttons, nil, nil, list)
+	d = dialog.NewCustomWithoutButtons("文件冲突", content, win)
+	d.Resize(fyne.NewSize(WINDOW_WIDTH*0.8, WINDOW_HEIGHT*0.8))
+	d.Show()
+}
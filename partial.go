@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 部分成功 (7zz 退出码 1) 相关代码
+// ---------------------------------------------------------
+
+// extractWarning 是解压时报告的一条错误或警告, name 为对应的压缩包内路径, 与具体条目无关时为空
+type extractWarning struct {
+	name   string
+	reason string
+}
+
+// archiveWarningPrefixes 是与具体条目无关, 但会让 7zz 以退出码 1 结束的提示
+var archiveWarningPrefixes = []string{
+	"There are data after the end of archive",
+	"There are some data after the end of the payload data",
+	"Unexpected end of archive",
+	"Headers Error",
+	"Sub items Errors:",
+}
+
+// parse7zzExtractWarnings 解析 7zz x 输出中的 ERROR: 与 WARNING: 行
+// 条目可能以压缩包内路径或解压后的磁盘路径出现, 例如
+// "ERROR: Data Error : dir/a.txt" 或 "ERROR: Can not open output file : Permission denied : /out/dir/a.txt"
+func parse7zzExtractWarnings(output string, req extractRequest) []extractWarning {
+	names := make(map[string]string, len(req.items))
+	for _, it := range req.items {
+		names[strings.Join(splitArchivePath(it.name), "/")] = it.name
+	}
+	dir := req.extractDir()
+	// lookup 把 7zz 报告的路径转换为压缩包内路径
+	lookup := func(p string) (string, bool) {
+		if rel, err := filepath.Rel(dir, p); err == nil && filepath.IsAbs(p) && !strings.HasPrefix(rel, "..") {
+			p = rel
+		}
+		name, ok := names[strings.Join(splitArchivePath(p), "/")]
+		return name, ok
+	}
+
+	var out []extractWarning
+	for _, raw := range strings.Split(output, "\n") {
+		line := strings.TrimSpace(raw)
+		msg, ok := strings.CutPrefix(line, "ERROR:")
+		if !ok {
+			msg, ok = strings.CutPrefix(line, "WARNING:")
+		}
+		if !ok {
+			for _, prefix := range archiveWarningPrefixes {
+				if strings.HasPrefix(line, prefix) {
+					out = append(out, extractWarning{reason: line})
+				}
+			}
+			continue
+		}
+
+		msg = strings.TrimSpace(msg)
+		parts := strings.Split(msg, " : ")
+		w := extractWarning{reason: msg}
+		for i := len(parts) - 1; i >= 0; i-- {
+			if name, ok := lookup(strings.TrimSpace(parts[i])); ok {
+				w.name = name
+				w.reason = strings.Join(append(append([]string(nil), parts[:i]...), parts[i+1:]...), " : ")
+				break
+			}
+		}
+		// 只有压缩包路径本身的 ERROR 行是后续提示的标题, 不单独列出
+		if w.name == "" && strings.TrimSpace(msg) == req.archivePath {
+			continue
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// isPartialSuccess 判断解压是否只有部分条目出错, 其余文件已正常解压
+// 退出码 1 总是如此; 条目出现数据错误, CRC 错误或无法写入时 7zz 以退出码 2 结束 ("Sub items Errors: N"),
+// 此时需要有对应到具体条目的错误, 否则是压缩包整体的错误
+func isPartialSuccess(err error, warnings []extractWarning) bool {
+	switch exitCodeOf(err) {
+	case exitWarning:
+		return true
+	case exitFatal:
+		for _, w := range warnings {
+			if w.name != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryFailed 返回只重新解压出错条目的请求, 没有可以重试的条目时返回 false
+// 智能解压改名到 smartTarget 后原位置已不对应, 无法只重试部分条目
+func (req extractRequest) retryFailed(warnings []extractWarning) (extractRequest, bool) {
+	if req.smartTarget != "" {
+		return req, false
+	}
+	seen := make(map[string]bool)
+	var paths []string
+	var size uint64
+	for _, w := range warnings {
+		if w.name == "" || seen[w.name] {
+			continue
+		}
+		seen[w.name] = true
+		paths = append(paths, w.name)
+	}
+	if len(paths) == 0 {
+		return req, false
+	}
+	for _, it := range req.items {
+		if seen[it.name] {
+			size += it.size
+		}
+	}
+
+	retry := req
+	retry.paths = paths
+	retry.excludes = nil
+	retry.totalSize = size
+	retry.testFirst = false
+	// 出错的文件可能只写了一半, 重试时直接覆盖
+	retry.overwrite = overwriteAll
+	return retry, true
+}
+
+// outputTail 返回 7zz 输出最后 n 个非空行, 用于退出码 1 但没有解析出任何警告时显示原始提示
+func outputTail(output string, n int) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// showExtractWarnings 显示部分成功的结果, onRetry 不为 nil 时提供"重试失败的条目"按钮
+// 没有解析出任何警告时改为显示 output 的最后几行
+func showExtractWarnings(win fyne.Window, resultDir string, warnings []extractWarning, output string, onRetry func(), onClose func()) {
+	if len(warnings) == 0 {
+		summary := widget.NewLabel("解压已完成, 但 7zz 报告了警告, 文件已解压到:\n" + resultDir)
+		summary.Wrapping = fyne.TextWrapWord
+		tail := readOnlyEntry(strings.Join(outputTail(output, WARNING_TAIL_LINES), "\n"))
+		d := dialog.NewCustom("完成, 有警告", "关闭", container.NewBorder(summary, nil, nil, nil, tail), win)
+		d.SetOnClosed(onClose)
+		d.Resize(fyne.NewSize(WINDOW_WIDTH*0.7, WINDOW_HEIGHT*0.6))
+		d.Show()
+		return
+	}
+
+	summary := widget.NewLabel(fmt.Sprintf("解压已完成, 但有 %d 个警告, 其余文件已解压到:\n%s", len(warnings), resultDir))
+	summary.Wrapping = fyne.TextWrapWord
+
+	list := widget.NewList(
+		func() int { return len(warnings) },
+		func() fyne.CanvasObject {
+			nameLbl := widget.NewLabel("")
+			nameLbl.TextStyle = fyne.TextStyle{Bold: true}
+			nameLbl.Truncation = fyne.TextTruncateEllipsis
+			reasonLbl := widget.NewLabel("")
+			reasonLbl.Truncation = fyne.TextTruncateEllipsis
+			return container.NewVBox(nameLbl, reasonLbl)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			box := obj.(*fyne.Container)
+			name := warnings[id].name
+			if name == "" {
+				name = "(压缩包)"
+			}
+			box.Objects[0].(*widget.Label).SetText(name)
+			box.Objects[1].(*widget.Label).SetText(warnings[id].reason)
+		},
+	)
+	content := container.NewBorder(summary, nil, nil, nil, list)
+
+	var d dialog.Dialog
+	if onRetry != nil {
+		d = dialog.NewCustomConfirm("完成, 有警告", "重试失败的条目", "关闭", content, func(ok bool) {
+			if ok {
+				onRetry()
+				return
+			}
+			onClose()
+		}, win)
+	} else {
+		d = dialog.NewCustom("完成, 有警告", "关闭", content, win)
+		d.SetOnClosed(onClose)
+	}
+	d.Resize(fyne.NewSize(WINDOW_WIDTH*0.7, WINDOW_HEIGHT*0.6))
+	d.Show()
+}
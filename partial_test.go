@@ -0,0 +1,59 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse7zzExtractWarnings(t *testing.T) {
+	out := t.TempDir()
+	req := extractRequest{
+		archivePath: "/data/a.7z",
+		items:       []archiveItem{{name: "dir", isDir: true}, {name: "dir/a.txt"}},
+		outputDir:   out,
+	}
+	staged := req
+	staged.stageDir = filepath.Join(out, ".7zgui-1")
+
+	cases := []struct {
+		name   string
+		req    extractRequest
+		output string
+		want   []extractWarning
+	}{
+		{"压缩包内路径", req, "ERROR: Data Error : dir/a.txt\n", []extractWarning{{"dir/a.txt", "Data Error"}}},
+		{"解压后的磁盘路径", req, "ERROR: Can not open output file : Permission denied : " + filepath.Join(out, "dir", "a.txt") + "\n",
+			[]extractWarning{{"dir/a.txt", "Can not open output file : Permission denied"}}},
+		{"临时目录中的磁盘路径", staged, "ERROR: Can not open output file : " + filepath.Join(staged.stageDir, "dir", "a.txt") + "\n",
+			[]extractWarning{{"dir/a.txt", "Can not open output file"}}},
+		{"未知的条目", req, "WARNING: Cannot open file : x.txt\n", []extractWarning{{"", "Cannot open file : x.txt"}}},
+		{"压缩包路径作为标题不单独列出", req, "ERROR: /data/a.7z\nERROR: Data Error : dir/a.txt\n", []extractWarning{{"dir/a.txt", "Data Error"}}},
+		{"与条目无关的提示", req, "Everything is Ok\nThere are data after the end of archive\nSub items Errors: 1\n",
+			[]extractWarning{{"", "There are data after the end of archive"}, {"", "Sub items Errors: 1"}}},
+		{"没有警告", req, "Everything is Ok\n", nil},
+	}
+	for _, c := range cases {
+		if got := parse7zzExtractWarnings(c.output, c.req); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: parse7zzExtractWarnings = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestOutputTail(t *testing.T) {
+	cases := []struct {
+		name   string
+		output string
+		n      int
+		want   []string
+	}{
+		{"空输出", "", 3, nil},
+		{"跳过空行", "a\n\n  b  \n\n", 3, []string{"a", "b"}},
+		{"只取最后几行", "1\n2\n3\n4\n", 2, []string{"3", "4"}},
+	}
+	for _, c := range cases {
+		if got := outputTail(c.output, c.n); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: outputTail = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
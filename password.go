@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+)
+
+// ---------------------------------------------------------
+// 密码缓存与通过标准输入传递密码相关代码
+// ---------------------------------------------------------
+
+// archiveKey 标识一个压缩包文件, 文件被替换或修改后缓存的密码不再使用
+type archiveKey struct {
+	path    string
+	size    int64
+	modTime int64
+}
+
+func archiveKeyOf(path string) (archiveKey, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return archiveKey{}, false
+	}
+	return archiveKey{path: path, size: info.Size(), modTime: info.ModTime().UnixNano()}, true
+}
+
+// 密码以 []byte 保存, 用完后可以清零: 列出, 解压, 测试, 打开嵌套压缩包, 预览和批量任务各自持有一份副本,
+// 操作结束时清零, 或交给 currentPassword 继续用于当前压缩包; 只有 sessionPasswords 和密码库会一直保留密码
+
+// encryptedListings 记录列出文件时就需要密码 (文件名已加密) 的压缩包, 只在 UI 线程访问
+var encryptedListings = make(map[string]bool)
+
+// sessionPasswords 是本次运行中已确认可用的密码, 只保存在内存中, 只在 UI 线程访问
+var sessionPasswords = make(map[archiveKey][]byte)
+
+// clonePassword 返回密码的副本, 交给一次操作使用, 操作结束时由它清零
+func clonePassword(password []byte) []byte {
+	if len(password) == 0 {
+		return nil
+	}
+	return bytes.Clone(password)
+}
+
+// setCurrentPassword 把 password 设为当前压缩包的密码并接管它, 清零原来的密码
+func setCurrentPassword(password []byte) {
+	if len(password) > 0 && len(currentPassword) > 0 && &password[0] == &currentPassword[0] {
+		return
+	}
+	clear(currentPassword)
+	currentPassword = password
+}
+
+// cachedPassword 返回本次运行中该压缩包用过的密码的副本, 没有时返回 nil
+func cachedPassword(archivePath string) []byte {
+	if key, ok := archiveKeyOf(archivePath); ok {
+		return clonePassword(sessionPasswords[key])
+	}
+	return nil
+}
+
+// rememberPassword 在密码确认可用 (verifyPassword 通过, 或解压, 测试成功) 后记入缓存, 之后再打开同一个压缩包时不再询问
+// 输入时勾选了"保存到密码库"的密码此时才存入密码库; 缓存保存的是副本, 调用方仍需清零自己的 password
+func rememberPassword(archivePath string, password []byte) {
+	if len(password) == 0 {
+		return
+	}
+	if key, ok := archiveKeyOf(archivePath); ok && !bytes.Equal(sessionPasswords[key], password) {
+		clear(sessionPasswords[key])
+		sessionPasswords[key] = clonePassword(password)
+	}
+	if pending, ok := keyringPending[archivePath]; ok && bytes.Equal(pending, password) {
+		clear(pending)
+		delete(keyringPending, archivePath)
+		if passwordKeyring.unlocked() {
+			_ = passwordKeyring.use(password)
+		}
+	}
+}
+
+// passwordPromptPattern 匹配 7zz 询问密码的提示, 提示后没有换行, 可能与后续输出在同一行
+var passwordPromptPattern = regexp.MustCompile(`Enter password[^:\n]*:[ \t]*`)
+
+// stripPasswordPrompt 去掉输出行中的密码提示
+func stripPasswordPrompt(line string) string {
+	return passwordPromptPattern.ReplaceAllString(line, "")
+}
+
+// passwordStdin 返回交给 7zz 作为标准输入的管道, 不再把密码放在命令行参数中, 避免被 ps 等看到
+// 进程启动后 (无论成功与否) 必须调用 send: 它把密码写入管道后清零写入用的缓冲区, 并关闭父进程持有的两端
+// password 仍归调用方所有, 由调用方在操作结束时清零
+// 没有密码时只写入换行, 加密的压缩包会按空密码处理并报告密码错误, 而不会一直等待输入
+// 压缩包没有加密时 7zz 不会读取标准输入, 写入的内容随进程结束丢弃
+func passwordStdin(password []byte) (stdin *os.File, send func(), err error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	buf := make([]byte, 0, len(password)+1)
+	buf = append(buf, password...)
+	buf = append(buf, '\n')
+	send = func() {
+		// 管道缓冲区远大于密码长度, 写入不会阻塞
+		_, _ = w.Write(buf)
+		clear(buf)
+		w.Close()
+		r.Close()
+	}
+	return r, send, nil
+}
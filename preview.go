@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// ---------------------------------------------------------
+// 文件预览相关代码
+// ---------------------------------------------------------
+
+const prefKeyShowPreview = "showPreview"
+
+type previewKind int
+
+const (
+	previewHex previewKind = iota
+	previewText
+	previewImage
+)
+
+var imageExts = []string{".png", ".jpg", ".jpeg", ".gif", ".bmp", ".webp"}
+
+var textExts = []string{
+	".txt", ".md", ".log", ".csv", ".tsv", ".json", ".xml", ".yaml", ".yml", ".toml", ".ini", ".cfg", ".conf",
+	".go", ".py", ".js", ".ts", ".c", ".h", ".cpp", ".hpp", ".cc", ".java", ".kt", ".rs", ".rb", ".php", ".swift",
+	".sh", ".bat", ".ps1", ".html", ".htm", ".css", ".sql", ".properties", ".gradle", ".mod", ".sum",
+}
+
+// isImageName 根据扩展名判断条目是否为可预览的图片
+func isImageName(name string) bool {
+	return hasExt(name, imageExts)
+}
+
+func hasExt(name string, exts []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range exts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// previewKindOf 根据扩展名选择预览方式, 未知扩展名先按十六进制处理, 读取后再判断是否为文本
+func previewKindOf(name string) previewKind {
+	switch {
+	case isImageName(name):
+		return previewImage
+	case hasExt(name, textExts):
+		return previewText
+	}
+	return previewHex
+}
+
+// looksLikeText 判断未知类型的内容是否可以按文本显示
+func looksLikeText(data []byte) bool {
+	if len(data) == 0 {
+		return true
+	}
+	if bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF}) {
+		return true
+	}
+	sample := data
+	if len(sample) > 8*1024 {
+		sample = sample[:8*1024]
+	}
+	return bytes.IndexByte(sample, 0) < 0
+}
+
+// decodeText 识别文本编码并转换为 UTF-8, 返回内容和编码名称
+// 依次检查 BOM 和 UTF-8, 都不是时按 GB18030 处理
+func decodeText(data []byte, truncated bool) (string, string) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(trimPartialRune(data[3:], truncated)), "UTF-8 (BOM)"
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		if s, err := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().Bytes(data); err == nil {
+			return string(s), "UTF-16 LE"
+		}
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		if s, err := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder().Bytes(data); err == nil {
+			return string(s), "UTF-16 BE"
+		}
+	}
+	if text := trimPartialRune(data, truncated); utf8.Valid(text) {
+		return string(text), "UTF-8"
+	}
+	if s, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data); err == nil {
+		return string(s), "GB18030"
+	}
+	return strings.ToValidUTF8(string(data), "�"), "未知"
+}
+
+// trimPartialRune 去掉截断时末尾不完整的 UTF-8 字符
+func trimPartialRune(data []byte, truncated bool) []byte {
+	if !truncated {
+		return data
+	}
+	for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+		data = data[:len(data)-1]
+	}
+	return data
+}
+
+// previewPane 显示在列表右侧, 预览当前选中的单个文件, 内容只读入内存
+type previewPane struct {
+	title     *widget.Label
+	info      *widget.Label
+	body      *fyne.Container
+	container *fyne.Container
+
+	node   *treeNode
+	cancel context.CancelFunc
+	seq    uint64
+}
+
+func newPreviewPane() *previewPane {
+	p := &previewPane{cancel: func() {}}
+	p.title = widget.NewLabel("")
+	p.title.TextStyle = fyne.TextStyle{Bold: true}
+	p.title.Truncation = fyne.TextTruncateEllipsis
+	p.info = widget.NewLabel("")
+	p.info.Truncation = fyne.TextTruncateEllipsis
+	p.body = container.NewStack()
+	p.container = container.NewBorder(container.NewVBox(p.title, p.info), nil, nil, nil, p.body)
+	p.clear()
+	return p
+}
+
+// clear 停止正在进行的预览并显示提示
+func (p *previewPane) clear() {
+	p.cancel()
+	p.seq++
+	p.node = nil
+	p.title.SetText("预览")
+	p.setMessage("选择一个文件进行预览")
+}
+
+func (p *previewPane) setMessage(msg string) {
+	p.info.SetText("")
+	lbl := widget.NewLabel(msg)
+	lbl.Wrapping = fyne.TextWrapWord
+	lbl.Alignment = fyne.TextAlignCenter
+	p.body.Objects = []fyne.CanvasObject{container.NewCenter(lbl)}
+	p.body.Refresh()
+}
+
+// show 在后台读取 node 的内容并显示, 同一个节点不会重复读取, password 只是借用, 读取时使用自己的副本
+func (p *previewPane) show(node *treeNode, archivePath string, password []byte, codePage int, unwrapTar bool) {
+	if node == p.node {
+		return
+	}
+	p.clear()
+	p.node = node
+	p.title.SetText(node.name)
+
+	kind := previewKindOf(node.name)
+	limit := uint64(PREVIEW_TEXT_MAX_BYTES)
+	if kind == previewImage {
+		if node.item.size > PREVIEW_IMAGE_MAX_BYTES {
+			p.setMessage(fmt.Sprintf("图片超过 %s, 不予预览", formatSize(PREVIEW_IMAGE_MAX_BYTES)))
+			return
+		}
+		limit = PREVIEW_IMAGE_MAX_BYTES
+	}
+	p.setMessage("正在读取...")
+
+	ctx, cancel := context.WithCancel(sessionCtx)
+	p.cancel = cancel
+	seq := p.seq
+	password = clonePassword(password)
+	go func() {
+		defer cancel()
+		defer clear(password)
+		data, truncated, output, err := archiver.Preview(ctx, archivePath, password, codePage, unwrapTar, node.item.name, limit)
+		canceled := ctx.Err() != nil && !truncated
+
+		fyne.Do(func() {
+			if seq != p.seq || canceled {
+				return
+			}
+			switch {
+			case err != nil && is7zzNotFound(err):
+				p.setMessage("找不到 7zz")
+			case needsPassword(output, err):
+				p.setMessage("文件已加密, 请先输入密码后再预览")
+			case err != nil:
+				msg := strings.TrimSpace(output)
+				if e := classify7zzError(output, err); e.kind != errUnknown {
+					msg = e.Error()
+				}
+				p.setMessage("无法读取: " + msg)
+			default:
+				p.render(kind, data, truncated)
+			}
+		})
+	}()
+}
+
+func (p *previewPane) render(kind previewKind, data []byte, truncated bool) {
+	if kind == previewHex && looksLikeText(data) {
+		kind = previewText
+	}
+
+	switch kind {
+	case previewImage:
+		img, format, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			p.setMessage("无法解码图片: " + err.Error())
+			return
+		}
+		b := img.Bounds()
+		p.info.SetText(fmt.Sprintf("%s  %d × %d", strings.ToUpper(format), b.Dx(), b.Dy()))
+		ci := canvas.NewImageFromImage(img)
+		ci.FillMode = canvas.ImageFillContain
+		p.body.Objects = []fyne.CanvasObject{ci}
+
+	case previewText:
+		text, enc := decodeText(data, truncated)
+		info := "编码: " + enc
+		if truncated {
+			info += fmt.Sprintf("  (只显示前 %s)", formatSize(uint64(len(data))))
+		}
+		p.info.SetText(info)
+		p.body.Objects = []fyne.CanvasObject{readOnlyEntry(text)}
+
+	default:
+		if len(data) > PREVIEW_HEX_MAX_BYTES {
+			data = data[:PREVIEW_HEX_MAX_BYTES]
+			truncated = true
+		}
+		info := "十六进制"
+		if truncated {
+			info += fmt.Sprintf("  (只显示前 %d 字节)", len(data))
+		}
+		p.info.SetText(info)
+		p.body.Objects = []fyne.CanvasObject{readOnlyEntry(hex.Dump(data))}
+	}
+	p.body.Refresh()
+}
+
+// readOnlyText 是不能编辑但没有禁用的多行文本框, 文字保持正常颜色, 仍然可以选择, 复制和滚动
+type readOnlyText struct {
+	widget.Entry
+}
+
+// TypedRune 忽略输入的字符
+func (e *readOnlyText) TypedRune(rune) {}
+
+// TypedKey 只处理移动光标和选择的按键
+func (e *readOnlyText) TypedKey(key *fyne.KeyEvent) {
+	switch key.Name {
+	case fyne.KeyUp, fyne.KeyDown, fyne.KeyLeft, fyne.KeyRight, fyne.KeyHome, fyne.KeyEnd, fyne.KeyPageUp, fyne.KeyPageDown:
+		e.Entry.TypedKey(key)
+	}
+}
+
+// TypedShortcut 只允许复制和全选, 右键菜单中的剪切和粘贴也会经过这里
+func (e *readOnlyText) TypedShortcut(s fyne.Shortcut) {
+	switch s.(type) {
+	case *fyne.ShortcutCopy, *fyne.ShortcutSelectAll:
+		e.Entry.TypedShortcut(s)
+	}
+}
+
+// readOnlyEntry 返回只读的多行文本框
+func readOnlyEntry(text string) fyne.CanvasObject {
+	e := &readOnlyText{}
+	e.MultiLine = true
+	e.TextStyle = fyne.TextStyle{Monospace: true}
+	e.Wrapping = fyne.TextWrapOff
+	e.ExtendBaseWidget(e)
+	e.SetText(text)
+	return e
+}
+
+// run7zzPreview 用 7zz e -so 读取单个条目, 最多读取 limit 字节
+// 读满后主动结束 7zz, 此时 truncated 为 true 且不返回错误
+func run7zzPreview(ctx context.Context, archivePath string, password []byte, codePage int, unwrapTar bool, name string, limit uint64) (data []byte, truncated bool, output string, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	args := []string{"e", archivePath, "-so", "-bsp0"}
+	if unwrapTar {
+		args = []string{"e", "-si", "-ttar", "-so", "-bsp0"}
+	}
+	args = append(args, codePageArgs(codePage)...)
+	args, cleanup, err := appendPathArgs(args, []string{name})
+	if err != nil {
+		return nil, false, "", err
+	}
+	defer cleanup()
+
+	stdin, send, err := passwordStdin(password)
+	if err != nil {
+		return nil, false, "", err
+	}
+	cmd := exec.CommandContext(ctx, sevenZipPath, args...)
+	out := newOutputCollector(nil, nil)
+	cmd.Stderr = out
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		send()
+		out.finish()
+		return nil, false, "", err
+	}
+
+	// 压缩的 tar 包需要先由另一个 7zz 解压缩, 再从标准输入读取 tar
+	var prod *exec.Cmd
+	if unwrapTar {
+		var r, w *os.File
+		r, w, err = os.Pipe()
+		if err != nil {
+			send()
+			out.finish()
+			return nil, false, "", err
+		}
+		prod = exec.CommandContext(ctx, sevenZipPath, unwrapTarArgs(archivePath)...)
+		prod.Stdin = stdin
+		prod.Stdout = w
+		prod.Stderr = out
+		cmd.Stdin = r
+		err = prod.Start()
+		if err == nil {
+			if err = cmd.Start(); err != nil {
+				_ = prod.Process.Kill()
+				_ = prod.Wait()
+			}
+		}
+		// 子进程已各自持有管道, 父进程关闭自己的副本
+		r.Close()
+		w.Close()
+	} else {
+		cmd.Stdin = stdin
+		err = cmd.Start()
+	}
+	send()
+	if err != nil {
+		out.finish()
+		return nil, false, "", err
+	}
+
+	data, _ = io.ReadAll(io.LimitReader(stdout, int64(limit)+1))
+	if uint64(len(data)) > limit {
+		data = data[:limit]
+		truncated = true
+		cancel()
+	}
+	err = cmd.Wait()
+	if prod != nil {
+		// 找到条目后 tar 的其余部分不再需要, 解压缩进程的错误只在读取失败时才有意义
+		if prodErr := prod.Wait(); err == nil && !truncated && len(data) == 0 {
+			err = prodErr
+		}
+	}
+	if truncated {
+		err = nil
+	}
+	return data, truncated, out.finish(), err
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 任务进度相关代码
+// ---------------------------------------------------------
+
+// 7zz -bsp1 输出的进度行, 例如 " 45% 12 - dir/file.txt" 或 " 3%"
+var progressLineRe = regexp.MustCompile(`^(\d{1,3})%(?:\s+\d+)?(?:\s+[-+=UTRD]\s+(.*))?$`)
+
+type progressInfo struct {
+	percent int
+	file    string
+}
+
+// parse7zzProgress 解析单个进度片段, 不是进度行时返回 false
+func parse7zzProgress(line string) (progressInfo, bool) {
+	line = strings.TrimSpace(line)
+	m := progressLineRe.FindStringSubmatch(line)
+	if m == nil {
+		return progressInfo{}, false
+	}
+	pct, err := strconv.Atoi(m[1])
+	if err != nil || pct > 100 {
+		return progressInfo{}, false
+	}
+	return progressInfo{percent: pct, file: strings.TrimSpace(m[2])}, true
+}
+
+// split7zzOutput 是 bufio.SplitFunc, 7zz 用退格符和回车刷新进度, 所以这几种字符都视为行尾
+func split7zzOutput(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	for i, b := range data {
+		if b == '\n' || b == '\r' || b == '\b' {
+			return i + 1, data[:i], nil
+		}
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// taskProgress 显示 7zz 任务的进度, 解压时替换底部的解压按钮栏, 也可以单独放进对话框
+type taskProgress struct {
+	idle      fyne.CanvasObject // 任务期间隐藏的内容, 可以为 nil
+	bar       *widget.ProgressBar
+	fileLbl   *widget.Label
+	statLbl   *widget.Label
+	cancelBtn *widget.Button
+	container *fyne.Container
+
+	totalSize uint64
+	started   time.Time
+	onCancel  func()
+}
+
+func newTaskProgress(idle fyne.CanvasObject) *taskProgress {
+	p := &taskProgress{idle: idle}
+
+	p.bar = widget.NewProgressBar()
+	p.fileLbl = widget.NewLabel("")
+	p.fileLbl.Truncation = fyne.TextTruncateEllipsis
+	p.statLbl = widget.NewLabel("")
+	p.cancelBtn = widget.NewButton("取消", func() {
+		if p.onCancel != nil {
+			p.cancelBtn.Disable()
+			p.onCancel()
+		}
+	})
+
+	bg := canvas.NewRectangle(parseHexColor(HEADER_BG_COLOR))
+	top := container.NewBorder(nil, nil, nil, p.cancelBtn, p.bar)
+	bottom := container.NewBorder(nil, nil, nil, p.statLbl, p.fileLbl)
+	p.container = container.NewStack(bg, container.NewVBox(top, bottom))
+	p.container.Hide()
+	return p
+}
+
+// start 显示进度面板, onCancel 在用户点击取消时调用
+func (p *taskProgress) start(totalSize uint64, onCancel func()) {
+	p.totalSize = totalSize
+	p.started = time.Now()
+	p.onCancel = onCancel
+	p.bar.SetValue(0)
+	p.fileLbl.SetText("准备中...")
+	p.statLbl.SetText("")
+	p.cancelBtn.Enable()
+	if p.idle != nil {
+		p.idle.Hide()
+	}
+	p.container.Show()
+}
+
+func (p *taskProgress) update(info progressInfo) {
+	p.bar.SetValue(float64(info.percent) / 100)
+	if info.file != "" {
+		p.fileLbl.SetText(info.file)
+	}
+
+	elapsed := time.Since(p.started)
+	if p.totalSize == 0 || info.percent <= 0 || elapsed <= 0 {
+		p.statLbl.SetText(fmt.Sprintf("已用时 %s", formatDuration(elapsed)))
+		return
+	}
+
+	done := p.totalSize * uint64(info.percent) / 100
+	speed := float64(done) / elapsed.Seconds()
+	eta := time.Duration(0)
+	if speed > 0 {
+		eta = time.Duration(float64(p.totalSize-done) / speed * float64(time.Second))
+	}
+	p.statLbl.SetText(fmt.Sprintf("%s/s  剩余 %s", formatSize(uint64(speed)), formatDuration(eta)))
+}
+
+// stop 隐藏进度面板并恢复被替换的内容
+func (p *taskProgress) stop() {
+	p.onCancel = nil
+	p.container.Hide()
+	if p.idle != nil {
+		p.idle.Show()
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	sec := int(d.Round(time.Second) / time.Second)
+	if sec < 0 {
+		sec = 0
+	}
+	h := sec / 3600
+	m := sec % 3600 / 60
+	s := sec % 60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
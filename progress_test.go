@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestParse7zzProgress(t *testing.T) {
+	cases := []struct {
+		line string
+		want progressInfo
+		ok   bool
+	}{
+		{" 45% 12 - dir/file.txt", progressInfo{percent: 45, file: "dir/file.txt"}, true},
+		{"  3%", progressInfo{percent: 3}, true},
+		{"100% 7", progressInfo{percent: 100}, true},
+		{" 12% + 新建 文件.txt ", progressInfo{percent: 12, file: "新建 文件.txt"}, true},
+		{"101%", progressInfo{}, false},
+		{"Everything is Ok", progressInfo{}, false},
+		{"- dir/file.txt", progressInfo{}, false},
+	}
+	for _, c := range cases {
+		got, ok := parse7zzProgress(c.line)
+		if ok != c.ok || got != c.want {
+			t.Errorf("parse7zzProgress(%q) = %+v, %v; want %+v, %v", c.line, got, ok, c.want, c.ok)
+		}
+	}
+}
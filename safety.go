@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 解压前的路径安全检查相关代码
+// ---------------------------------------------------------
+
+type unsafeReason int
+
+const (
+	unsafeAbsolute      unsafeReason = iota // 绝对路径或带盘符的路径
+	unsafeParent                            // 路径中包含 ..
+	unsafeLink                              // 链接指向目标文件夹之外, 或无法确定指向
+	unsafeDevice                            // 设备文件, 管道等特殊文件
+	unsafeCaseCollision                     // 忽略大小写后与其他条目重名
+)
+
+var unsafeReasonLabels = []string{"绝对路径", "路径包含 ..", "链接指向目标文件夹之外", "设备或特殊文件", "忽略大小写后重名"}
+
+// unsafeEntry 是一个有风险的条目, detail 为补充说明
+type unsafeEntry struct {
+	item   archiveItem
+	reason unsafeReason
+	detail string
+}
+
+func (e unsafeEntry) describe() string {
+	if e.detail == "" {
+		return unsafeReasonLabels[e.reason]
+	}
+	return unsafeReasonLabels[e.reason] + ": " + e.detail
+}
+
+// unixModePattern 匹配 Attributes 中的 unix 权限字符串, 例如 lrwxrwxrwx
+var unixModePattern = regexp.MustCompile(`(?:^|\s)([-dlcbps])[-rwxsStT]{9}(?:\s|$)`)
+
+// unixFileType 返回 unix 权限字符串的类型字符, 没有 unix 属性时返回 0
+func unixFileType(attr string) byte {
+	m := unixModePattern.FindStringSubmatch(attr)
+	if m == nil {
+		return 0
+	}
+	return m[1][0]
+}
+
+// isAbsArchivePath 判断路径是否以 / 或 \ 开头, 或带有 C: 这样的盘符
+func isAbsArchivePath(p string) bool {
+	if strings.HasPrefix(p, "/") || strings.HasPrefix(p, `\`) {
+		return true
+	}
+	return len(p) >= 2 && p[1] == ':' && ('a' <= p[0]|0x20 && p[0]|0x20 <= 'z')
+}
+
+func hasParentRef(p string) bool {
+	for _, part := range strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// linkEscapes 判断位于 dir 的链接按 target 解析后是否会离开解压目录
+func linkEscapes(dir []string, target string) bool {
+	if isAbsArchivePath(target) {
+		return true
+	}
+	depth := len(dir)
+	for _, part := range strings.FieldsFunc(target, func(r rune) bool { return r == '/' || r == '\\' }) {
+		switch part {
+		case ".":
+		case "..":
+			depth--
+			if depth < 0 {
+				return true
+			}
+		default:
+			depth++
+		}
+	}
+	return false
+}
+
+// findUnsafeEntries 检查将被解压的条目, 找出可能写到目标文件夹之外或覆盖彼此的条目
+func findUnsafeEntries(req extractRequest) []unsafeEntry {
+	var out []unsafeEntry
+	seen := make(map[string]string)
+	for _, it := range req.items {
+		if !isUnderPaths(it.name, req.paths) || (len(req.excludes) > 0 && isUnderPaths(it.name, req.excludes)) {
+			continue
+		}
+
+		switch {
+		case isAbsArchivePath(it.name):
+			out = append(out, unsafeEntry{item: it, reason: unsafeAbsolute})
+			continue
+		case hasParentRef(it.name):
+			out = append(out, unsafeEntry{item: it, reason: unsafeParent})
+			continue
+		}
+
+		// 只由 . 和分隔符组成的名称 (例如 "./.") 指向解压目录本身, 没有可检查的内容
+		parts := splitArchivePath(it.name)
+		if len(parts) == 0 {
+			continue
+		}
+		switch unixFileType(it.attr) {
+		case 'c', 'b', 'p', 's':
+			out = append(out, unsafeEntry{item: it, reason: unsafeDevice, detail: it.attr})
+			continue
+		case 'l':
+			if it.link == "" {
+				out = append(out, unsafeEntry{item: it, reason: unsafeLink, detail: "无法确定链接目标"})
+				continue
+			}
+		}
+		// 硬链接的目标从压缩包根目录解析, 符号链接从链接所在目录解析
+		linkDir := parts[:len(parts)-1]
+		if it.hardLink {
+			linkDir = nil
+		}
+		if it.link != "" && linkEscapes(linkDir, it.link) {
+			out = append(out, unsafeEntry{item: it, reason: unsafeLink, detail: "-> " + it.link})
+			continue
+		}
+
+		key := strings.ToLower(strings.Join(parts, "/"))
+		if first, ok := seen[key]; ok {
+			if first != it.name {
+				out = append(out, unsafeEntry{item: it, reason: unsafeCaseCollision, detail: "与 " + first + " 冲突"})
+			}
+			continue
+		}
+		seen[key] = it.name
+	}
+	return out
+}
+
+// sanitize 调整解压请求, 使有风险的条目可以安全地解压
+// 绝对路径和 .. 不需要处理, 7zz 在没有 -spf 时会把它们转换为目标文件夹内的相对路径;
+// 链接和特殊文件无法安全还原, 直接跳过; 大小写重名时跳过后出现的条目, 避免在不区分大小写的磁盘上互相覆盖,
+// 其余文件仍按用户选择的覆盖方式处理
+func (req *extractRequest) sanitize(entries []unsafeEntry) {
+	for _, e := range entries {
+		switch e.reason {
+		case unsafeLink, unsafeDevice, unsafeCaseCollision:
+			req.excludes = append(req.excludes, e.item.name)
+		}
+	}
+}
+
+func unsafeNames(entries []unsafeEntry) []string {
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.item.name
+	}
+	return names
+}
+
+// showSafetyDialog 列出有风险的条目, 由用户选择跳过, 清理后解压或中止
+func showSafetyDialog(win fyne.Window, entries []unsafeEntry, onSkip func(), onSanitize func(), onAbort func()) {
+	list := widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject {
+			nameLbl := widget.NewLabel("")
+			nameLbl.TextStyle = fyne.TextStyle{Bold: true}
+			nameLbl.Truncation = fyne.TextTruncateEllipsis
+			infoLbl := widget.NewLabel("")
+			infoLbl.Truncation = fyne.TextTruncateEllipsis
+			return container.NewVBox(nameLbl, infoLbl)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			box := obj.(*fyne.Container)
+			box.Objects[0].(*widget.Label).SetText(entries[id].item.name)
+			box.Objects[1].(*widget.Label).SetText(entries[id].describe())
+		},
+	)
+
+	msg := widget.NewLabel(fmt.Sprintf("压缩包中有 %d 个条目可能写到目标文件夹之外或互相覆盖, 请选择处理方式:\n"+
+		"清理后解压: 绝对路径和 .. 转换为目标文件夹内的相对路径, 链接, 特殊文件和大小写重名的后一个文件被跳过", len(entries)))
+	msg.Wrapping = fyne.TextWrapWord
+
+	var d dialog.Dialog
+	finish := func(f func()) func() {
+		return func() {
+			d.Hide()
+			f()
+		}
+	}
+	skipBtn := widget.NewButton("跳过这些条目", finish(onSkip))
+	sanitizeBtn := widget.NewButton("清理后解压", finish(onSanitize))
+	sanitizeBtn.Importance = widget.HighImportance
+	abortBtn := widget.NewButton("中止", finish(onAbort))
+	abortBtn.Importance = widget.DangerImportance
+
+	content := container.NewBorder(msg, container.NewGridWithColumns(3, skipBtn, sanitizeBtn, abortBtn), nil, nil, list)
+	d = dialog.NewCustomWithoutButtons("安全警告", content, win)
+	d.Resize(fyne.NewSize(WINDOW_WIDTH*0.8, WINDOW_HEIGHT*0.8))
+	d.Show()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindUnsafeEntries(t *testing.T) {
+	cases := []struct {
+		name string
+		item archiveItem
+		want []unsafeReason
+	}{
+		{"普通文件", archiveItem{name: "docs/a.txt"}, nil},
+		{"指向自身的名称", archiveItem{name: "./."}, nil},
+		{"只有分隔符", archiveItem{name: ".//"}, nil},
+		{"绝对路径", archiveItem{name: "/etc/passwd"}, []unsafeReason{unsafeAbsolute}},
+		{"盘符", archiveItem{name: `C:\Windows\a.dll`}, []unsafeReason{unsafeAbsolute}},
+		{"上级目录", archiveItem{name: "docs/../../a.txt"}, []unsafeReason{unsafeParent}},
+		{"设备文件", archiveItem{name: "dev/null", attr: "A crw-rw-rw-"}, []unsafeReason{unsafeDevice}},
+		{"链接到外部", archiveItem{name: "docs/link", attr: "A lrwxrwxrwx", link: "../../etc/passwd"}, []unsafeReason{unsafeLink}},
+		{"链接在内部", archiveItem{name: "docs/link", attr: "A lrwxrwxrwx", link: "../readme.txt"}, nil},
+		{"硬链接从根目录解析", archiveItem{name: "docs/hard", link: "../a.txt", hardLink: true}, []unsafeReason{unsafeLink}},
+		{"没有目标的链接", archiveItem{name: "docs/link", attr: "A lrwxrwxrwx"}, []unsafeReason{unsafeLink}},
+	}
+	for _, c := range cases {
+		got := findUnsafeEntries(extractRequest{items: []archiveItem{c.item}})
+		if len(got) != len(c.want) {
+			t.Errorf("%s: findUnsafeEntries = %+v, want %v", c.name, got, c.want)
+			continue
+		}
+		for i, e := range got {
+			if e.reason != c.want[i] {
+				t.Errorf("%s: reason = %d, want %d", c.name, e.reason, c.want[i])
+			}
+		}
+	}
+
+	items := []archiveItem{{name: "Readme.txt"}, {name: "readme.TXT"}, {name: "./."}}
+	got := findUnsafeEntries(extractRequest{items: items})
+	if len(got) != 1 || got[0].reason != unsafeCaseCollision || got[0].item.name != "readme.TXT" {
+		t.Errorf("大小写重名: findUnsafeEntries = %+v", got)
+	}
+}
+
+// TestSanitizeKeepsOverwriteMode 清理后解压只跳过有风险的条目, 不改变用户选择的覆盖方式
+func TestSanitizeKeepsOverwriteMode(t *testing.T) {
+	items := []archiveItem{
+		{name: "Readme.txt"},
+		{name: "readme.TXT"},
+		{name: "docs/link", attr: "A lrwxrwxrwx", link: "../../etc/passwd"},
+		{name: "docs/a.txt"},
+	}
+	for _, mode := range []overwriteMode{overwriteAsk, overwriteAll} {
+		req := extractRequest{items: items, overwrite: mode}
+		req.sanitize(findUnsafeEntries(req))
+		if req.overwrite != mode {
+			t.Errorf("overwrite = %d, want %d", req.overwrite, mode)
+		}
+		if want := []string{"readme.TXT", "docs/link"}; !reflect.DeepEqual(req.excludes, want) {
+			t.Errorf("excludes = %q, want %q", req.excludes, want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 列表勾选与多选相关代码
+// ---------------------------------------------------------
+
+// fileRow 是列表中的一行, 左侧为勾选框, 右侧为各列内容
+// 单击按修饰键处理选择, 双击进入文件夹
+type fileRow struct {
+	widget.BaseWidget
+
+	id    widget.ListItemID
+	bg    *canvas.Rectangle
+	check *widget.Check
+	cells *fyne.Container
+
+	modifier       fyne.KeyModifier
+	onTapped       func(id widget.ListItemID, mod fyne.KeyModifier)
+	onDoubleTapped func(id widget.ListItemID)
+}
+
+func newFileRow(cells *fyne.Container) *fileRow {
+	r := &fileRow{
+		id:    -1,
+		bg:    canvas.NewRectangle(theme.SelectionColor()),
+		check: widget.NewCheck("", nil),
+		cells: cells,
+	}
+	r.bg.Hide()
+	r.ExtendBaseWidget(r)
+	return r
+}
+
+func (r *fileRow) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewStack(r.bg,
+		container.NewBorder(nil, nil, r.check, nil, r.cells)))
+}
+
+// setChecked 更新勾选状态但不触发 OnChanged
+func (r *fileRow) setChecked(checked bool, inherited bool, onChanged func(bool)) {
+	r.check.OnChanged = nil
+	r.check.SetChecked(checked)
+	r.check.OnChanged = onChanged
+	// 由上级文件夹带入的勾选不能单独取消
+	if inherited {
+		r.check.Disable()
+	} else {
+		r.check.Enable()
+	}
+	if checked {
+		r.bg.Show()
+	} else {
+		r.bg.Hide()
+	}
+}
+
+func (r *fileRow) MouseDown(ev *desktop.MouseEvent) { r.modifier = ev.Modifier }
+func (r *fileRow) MouseUp(*desktop.MouseEvent)      {}
+
+func (r *fileRow) Tapped(*fyne.PointEvent) {
+	mod := r.modifier
+	r.modifier = 0
+	if r.onTapped != nil {
+		r.onTapped(r.id, mod)
+	}
+}
+
+func (r *fileRow) DoubleTapped(*fyne.PointEvent) {
+	r.modifier = 0
+	if r.onDoubleTapped != nil {
+		r.onDoubleTapped(r.id)
+	}
+}
+
+// checkboxWidth 返回勾选框占用的宽度, 表头需要留出同样的空间
+func checkboxWidth() float32 {
+	return widget.NewCheck("", nil).MinSize().Width
+}
+
+// isChecked 返回节点是否被勾选, 以及勾选是否来自上级文件夹
+func (b *archiveBrowser) isChecked(n *treeNode) (checked bool, inherited bool) {
+	if b.checked[n] {
+		return true, false
+	}
+	for p := n.parent; p != nil; p = p.parent {
+		if b.checked[p] {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// setChecked 勾选或取消勾选一个节点, 勾选文件夹时其子孙节点的单独勾选会被合并
+func (b *archiveBrowser) setChecked(n *treeNode, checked bool) {
+	if b.checked == nil {
+		b.checked = make(map[*treeNode]bool)
+	}
+	if !checked {
+		delete(b.checked, n)
+		return
+	}
+	for c := range b.checked {
+		for p := c.parent; p != nil; p = p.parent {
+			if p == n {
+				delete(b.checked, c)
+				break
+			}
+		}
+	}
+	b.checked[n] = true
+}
+
+// tapRow 按文件管理器的习惯处理单击:
+// 普通单击只选中当前行, Ctrl/Cmd 单击切换当前行, Shift 单击选中从锚点到当前行的范围
+func (b *archiveBrowser) tapRow(id widget.ListItemID, mod fyne.KeyModifier) {
+	n := b.row(id)
+	if n == nil {
+		return
+	}
+	if _, inherited := b.isChecked(n); inherited {
+		return
+	}
+
+	switch {
+	case mod&fyne.KeyModifierShift != 0 && b.anchor >= 0 && b.anchor < len(b.rows):
+		lo, hi := b.anchor, id
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if mod&(fyne.KeyModifierControl|fyne.KeyModifierSuper) == 0 {
+			b.clearChecked()
+		}
+		for i := lo; i <= hi; i++ {
+			b.setChecked(b.rows[i], true)
+		}
+	case mod&(fyne.KeyModifierControl|fyne.KeyModifierSuper) != 0:
+		b.setChecked(n, !b.checked[n])
+		b.anchor = id
+	default:
+		b.clearChecked()
+		b.setChecked(n, true)
+		b.anchor = id
+	}
+	b.selectionChanged()
+}
+
+// toggleRow 处理勾选框的点击, 只影响当前行
+func (b *archiveBrowser) toggleRow(n *treeNode, checked bool) {
+	b.setChecked(n, checked)
+	b.anchor = -1
+	for i, r := range b.rows {
+		if r == n {
+			b.anchor = i
+			break
+		}
+	}
+	b.selectionChanged()
+}
+
+func (b *archiveBrowser) clearChecked() {
+	for n := range b.checked {
+		delete(b.checked, n)
+	}
+}
+
+func (b *archiveBrowser) selectionChanged() {
+	b.list.Refresh()
+	if b.onSelectionChanged != nil {
+		b.onSelectionChanged()
+	}
+}
+
+// selectedNodes 返回勾选的最上层节点, 文件夹代表其整个子树
+func (b *archiveBrowser) selectedNodes() []*treeNode {
+	var out []*treeNode
+	var walk func(n *treeNode)
+	walk = func(n *treeNode) {
+		for _, c := range n.children {
+			if b.checked[c] {
+				out = append(out, c)
+				continue
+			}
+			walk(c)
+		}
+	}
+	if b.root != nil {
+		walk(b.root)
+	}
+	return out
+}
+
+// selectedPaths 返回传给 7zz 的压缩包内路径, 7zz 匹配到文件夹时会包含其下所有内容
+func (b *archiveBrowser) selectedPaths() []string {
+	nodes := b.selectedNodes()
+	paths := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		paths = append(paths, n.item.name)
+	}
+	return paths
+}
+
+func (b *archiveBrowser) selectedSize() uint64 {
+	var total uint64
+	for _, n := range b.selectedNodes() {
+		total += n.totalSize
+	}
+	return total
+}
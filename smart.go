@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+)
+
+// ---------------------------------------------------------
+// 输出目录布局 (含智能解压) 相关代码
+// ---------------------------------------------------------
+
+// outputLayout 决定解压内容在所选文件夹下如何摆放
+type outputLayout int
+
+const (
+	layoutSubfolder outputLayout = iota // 解压到与压缩包同名的子文件夹
+	layoutDirect                        // 直接解压到所选文件夹
+	layoutSmart                         // 只有一个顶层文件夹时直接解压, 否则创建同名子文件夹
+)
+
+const prefKeySmartExtract = "smartExtract"
+
+var outputLayoutLabels = []string{"解压到同名子文件夹", "直接解压到该文件夹", "智能 (避免重复嵌套)"}
+
+// loadDefaultLayout 返回"解压到当前目录"等按钮使用的布局, 由底部的智能解压开关决定
+func loadDefaultLayout() outputLayout {
+	if fyne.CurrentApp().Preferences().Bool(prefKeySmartExtract) {
+		return layoutSmart
+	}
+	return layoutSubfolder
+}
+
+// singleTopDir 在将被解压的条目只有一个顶层目录时返回它的名称
+func singleTopDir(items []archiveItem, paths []string) (string, bool) {
+	top := ""
+	isDir := false
+	for _, it := range items {
+		if !isUnderPaths(it.name, paths) {
+			continue
+		}
+		parts := splitArchivePath(it.name)
+		if len(parts) == 0 {
+			continue
+		}
+		if top == "" {
+			top = parts[0]
+		} else if parts[0] != top {
+			return "", false
+		}
+		if len(parts) > 1 || it.isDir {
+			isDir = true
+		}
+	}
+	return top, top != "" && isDir
+}
+
+// uniquePath 在 p 已存在时依次尝试 "p (2)", "p (3)" ... 返回第一个不存在的路径
+func uniquePath(p string) string {
+	if _, err := os.Lstat(p); os.IsNotExist(err) {
+		return p
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)", p, i)
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// planOutput 根据所选文件夹与布局设置 req 的解压目录
+// 智能模式下顶层文件夹与已有路径重名时, 先解压到临时目录, 完成后再改名为带编号的文件夹
+func planOutput(req extractRequest, chosen string, layout outputLayout) extractRequest {
+	req.smartTop = ""
+	req.smartTarget = ""
+	req.stageDir = ""
+
+	switch layout {
+	case layoutDirect:
+		req.outputDir = chosen
+		return req
+	case layoutSmart:
+		top, ok := singleTopDir(req.items, req.paths)
+		if !ok {
+			break
+		}
+		req.outputDir = chosen
+		req.smartTop = top
+		target := filepath.Join(chosen, top)
+		if _, err := os.Lstat(target); err == nil {
+			req.smartTarget = uniquePath(target)
+		}
+		return req
+	}
+	req.outputDir = filepath.Join(chosen, filepath.Base(defaultOutputDir(req.archivePath)))
+	return req
+}
+
+// resultDir 返回解压完成后展示给用户的目录
+func (req extractRequest) resultDir() string {
+	switch {
+	case req.smartTarget != "":
+		return req.smartTarget
+	case req.smartTop != "":
+		return filepath.Join(req.outputDir, req.smartTop)
+	}
+	return req.outputDir
+}
+
+// diskPath 返回压缩包内条目解压后在磁盘上的最终路径
+func (req extractRequest) diskPath(name string) string {
+	parts := splitArchivePath(name)
+	if req.smartTarget != "" && len(parts) > 0 && parts[0] == req.smartTop {
+		return filepath.Join(req.smartTarget, filepath.FromSlash(strings.Join(parts[1:], "/")))
+	}
+	return filepath.Join(req.outputDir, filepath.FromSlash(strings.Join(parts, "/")))
+}
+
+// prepareStage 在需要改名时创建临时解压目录
+func (req *extractRequest) prepareStage() error {
+	if req.smartTarget == "" || req.stageDir != "" {
+		return nil
+	}
+	stage, err := os.MkdirTemp(req.outputDir, ".7zgui-")
+	if err != nil {
+		return err
+	}
+	req.stageDir = stage
+	return nil
+}
+
+// extractDir 返回实际传给 7zz 的解压目录
+func (req extractRequest) extractDir() string {
+	if req.stageDir != "" {
+		return req.stageDir
+	}
+	return req.outputDir
+}
+
+// finishStage 把临时目录中的顶层文件夹改名为最终目录, 并删除临时目录
+func (req extractRequest) finishStage() error {
+	if req.stageDir == "" {
+		return nil
+	}
+	src := filepath.Join(req.stageDir, req.smartTop)
+	if _, err := os.Lstat(src); err == nil {
+		if err := os.Rename(src, req.smartTarget); err != nil {
+			return err
+		}
+	}
+	return os.Remove(req.stageDir)
+}
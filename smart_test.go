@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanOutput(t *testing.T) {
+	nested := []archiveItem{{name: "photos", isDir: true}, {name: "photos/a.jpg"}, {name: "photos/2024/b.jpg"}}
+	flat := []archiveItem{{name: "a.txt"}, {name: "b.txt"}}
+	mixed := []archiveItem{{name: "photos/a.jpg"}, {name: "notes/b.txt"}}
+
+	cases := []struct {
+		name     string
+		items    []archiveItem
+		paths    []string
+		layout   outputLayout
+		existing []string
+		// 以下路径相对于所选文件夹
+		outputDir string
+		smartTop  string
+		target    string
+		result    string
+	}{
+		{"同名子文件夹", nested, nil, layoutSubfolder, nil, "album", "", "", "album"},
+		{"直接解压", nested, nil, layoutDirect, nil, ".", "", "", "."},
+		{"智能, 只有一个顶层文件夹", nested, nil, layoutSmart, nil, ".", "photos", "", "photos"},
+		{"智能, 顶层文件夹没有自己的条目", nested[1:], nil, layoutSmart, nil, ".", "photos", "", "photos"},
+		{"智能, 顶层文件夹已存在", nested, nil, layoutSmart, []string{"photos"}, ".", "photos", "photos (2)", "photos (2)"},
+		{"智能, 编号的文件夹也已存在", nested, nil, layoutSmart, []string{"photos", "photos (2)"}, ".", "photos", "photos (3)", "photos (3)"},
+		{"智能, 顶层有多个文件", flat, nil, layoutSmart, nil, "album", "", "", "album"},
+		{"智能, 只有一个顶层文件", flat[:1], nil, layoutSmart, nil, "album", "", "", "album"},
+		{"智能, 多个顶层文件夹", mixed, nil, layoutSmart, nil, "album", "", "", "album"},
+		{"智能, 只解压其中一个文件夹", mixed, []string{"notes"}, layoutSmart, nil, ".", "notes", "", "notes"},
+	}
+	for _, c := range cases {
+		chosen := t.TempDir()
+		for _, e := range c.existing {
+			if err := os.Mkdir(filepath.Join(chosen, e), 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// 上一次规划留下的状态必须被清除
+		stale := extractRequest{
+			archivePath: filepath.Join(t.TempDir(), "album.tar.gz"),
+			items:       c.items,
+			paths:       c.paths,
+			smartTop:    "old",
+			smartTarget: "/old (2)",
+			stageDir:    "/old/.7zgui-1",
+		}
+		req := planOutput(stale, chosen, c.layout)
+
+		rel := func(p string) string {
+			if p == "" {
+				return ""
+			}
+			return filepath.Join(chosen, p)
+		}
+		if req.outputDir != rel(c.outputDir) || req.smartTop != c.smartTop || req.smartTarget != rel(c.target) || req.stageDir != "" {
+			t.Errorf("%s: outputDir = %q, smartTop = %q, smartTarget = %q, stageDir = %q", c.name, req.outputDir, req.smartTop, req.smartTarget, req.stageDir)
+		}
+		if got := req.resultDir(); got != rel(c.result) {
+			t.Errorf("%s: resultDir = %q, want %q", c.name, got, rel(c.result))
+		}
+	}
+}
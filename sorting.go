@@ -0,0 +1,170 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 列表排序相关代码
+// ---------------------------------------------------------
+
+const (
+	prefKeySortColumn = "sortColumn"
+	prefKeySortDesc   = "sortDesc"
+)
+
+// 排序列与表头顺序一致, sortNone 表示保持 7zz 列出的顺序
+const (
+	sortNone = iota - 1
+	sortName
+	sortSize
+	sortUnpacked
+	sortModified
+	sortType
+)
+
+// headerCell 是可以点击的表头标签, 点击时切换排序
+type headerCell struct {
+	widget.Label
+	onTapped func()
+}
+
+func newHeaderCell(text string, onTapped func()) *headerCell {
+	c := &headerCell{onTapped: onTapped}
+	c.Text = text
+	c.TextStyle = fyne.TextStyle{Bold: true}
+	c.Alignment = fyne.TextAlignLeading
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+func (c *headerCell) Tapped(*fyne.PointEvent) {
+	if c.onTapped != nil {
+		c.onTapped()
+	}
+}
+
+func (c *headerCell) Cursor() desktop.Cursor { return desktop.PointerCursor }
+
+// loadSort 读取上次使用的排序方式, 对所有压缩包生效
+func loadSort() (col int, desc bool) {
+	prefs := fyne.CurrentApp().Preferences()
+	col = prefs.IntWithFallback(prefKeySortColumn, sortNone)
+	if col < sortNone || col > sortType {
+		col = sortNone
+	}
+	return col, prefs.Bool(prefKeySortDesc)
+}
+
+func saveSort(col int, desc bool) {
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetInt(prefKeySortColumn, col)
+	prefs.SetBool(prefKeySortDesc, desc)
+}
+
+// setSort 更新排序方式并刷新列表
+func (b *archiveBrowser) setSort(col int, desc bool) {
+	b.sortCol = col
+	b.sortDesc = desc
+	b.refreshRows()
+	b.list.Refresh()
+}
+
+// sortRows 按当前排序方式排列 rows, 文件夹总是排在文件前面
+func (b *archiveBrowser) sortRows(rows []*treeNode) {
+	if b.sortCol == sortNone {
+		return
+	}
+	cmp := func(x, y *treeNode) int {
+		switch b.sortCol {
+		case sortSize:
+			return compareUint(x.totalPacked, y.totalPacked)
+		case sortUnpacked:
+			return compareUint(x.totalSize, y.totalSize)
+		case sortModified:
+			return x.modTime.Compare(y.modTime)
+		case sortType:
+			return naturalCompare(filepath.Ext(x.name), filepath.Ext(y.name))
+		}
+		return 0
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		x, y := rows[i], rows[j]
+		if x.item.isDir != y.item.isDir {
+			return x.item.isDir
+		}
+		c := cmp(x, y)
+		if c == 0 {
+			c = naturalCompare(x.name, y.name)
+		}
+		if b.sortDesc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// naturalCompare 按自然顺序比较名称, 数字部分按数值比较 (file2 在 file10 之前), 不区分大小写
+func naturalCompare(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if unicode.IsDigit(ra[i]) && unicode.IsDigit(rb[j]) {
+			si := i
+			for i < len(ra) && unicode.IsDigit(ra[i]) {
+				i++
+			}
+			sj := j
+			for j < len(rb) && unicode.IsDigit(rb[j]) {
+				j++
+			}
+			// 去掉前导零后先比较位数, 再逐位比较
+			na := strings.TrimLeft(string(ra[si:i]), "0")
+			nb := strings.TrimLeft(string(rb[sj:j]), "0")
+			if len(na) != len(nb) {
+				return compareInt(len(na), len(nb))
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			continue
+		}
+		ca, cb := unicode.ToLower(ra[i]), unicode.ToLower(rb[j])
+		if ca != cb {
+			return compareInt(int(ca), int(cb))
+		}
+		i++
+		j++
+	}
+	if c := compareInt(len(ra)-i, len(rb)-j); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
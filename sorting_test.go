@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestNaturalCompare(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"file2", "file10", -1},
+		{"file10", "file9", 1},
+		{"File2", "file10", -1},
+		{"a007", "a10", -1},
+		{"a010", "a9", 1},
+		{"v1.02", "v1.10", -1},
+		{"0", "00", -1},
+		{"file01", "file1", -1},
+		{"file001.txt", "file1.txt", -1},
+		{"a", "a1", -1},
+		{"a1b", "a1", 1},
+		{"abc", "ABD", -1},
+		{"ABC", "abc", -1},
+		{"第2章", "第10章", -1},
+		{"same", "same", 0},
+	}
+	for _, c := range cases {
+		if got := naturalCompare(c.a, c.b); got != c.want {
+			t.Errorf("naturalCompare(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+		if got := naturalCompare(c.b, c.a); got != -c.want {
+			t.Errorf("naturalCompare(%q, %q) = %d, want %d", c.b, c.a, got, -c.want)
+		}
+	}
+}
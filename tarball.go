@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"strings"
+)
+
+// ---------------------------------------------------------
+// 压缩的 tar 包 (.tar.gz, .tar.xz, .tar.zst ...) 相关代码
+// ---------------------------------------------------------
+
+// tarWrapperTypes 是只包含单个数据流的压缩格式, 7zz 打开时只能看到里面的 .tar
+var tarWrapperTypes = map[string]bool{
+	"gzip": true, "bzip2": true, "xz": true, "zstd": true, "lz4": true, "lzma": true,
+}
+
+// parse7zzArchiveType 返回 7zz l -slt 输出中压缩包本身的类型, 例如 gzip
+func parse7zzArchiveType(output string) string {
+	for _, raw := range strings.Split(output, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "----------" {
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "Type = "); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// isTarWrapper 判断列表结果是否为压缩流中只有一个 tar
+func isTarWrapper(archivePath string, output string) bool {
+	if !tarWrapperTypes[strings.ToLower(parse7zzArchiveType(output))] {
+		return false
+	}
+	items := parse7zzListSlt(output)
+	if len(items) != 1 || items[0].isDir {
+		return false
+	}
+	if strings.HasSuffix(strings.ToLower(items[0].name), ".tar") {
+		return true
+	}
+	switch detectArchiveSuffix(archivePath) {
+	case ".tar.gz", ".tar.bz2", ".tar.xz", ".tar.zst", ".tar.lz4", ".tar.lzma", ".tgz", ".tbz2", ".txz", ".tzst":
+		return true
+	}
+	return false
+}
+
+// unwrapTarArgs 返回把压缩流解压到标准输出的 7zz 参数, 进度输出到标准错误, 密码从标准输入读取
+func unwrapTarArgs(archivePath string) []string {
+	return []string{"x", archivePath, "-so", "-bso0", "-bsp2"}
+}
+
+// listArchive 列出压缩包内容, 外层是单个 tar 的压缩流时改为列出 tar 里的内容
+// unwrapTar 为 true 表示返回的是 tar 的列表, 解压时需要走管道
+func listArchive(ctx context.Context, archivePath string, password []byte, codePage int, onStall func(stalled bool)) (output string, unwrapTar bool, err error) {
+	output, err = run7zzList(ctx, archivePath, password, codePage, onStall)
+	if err != nil || !isTarWrapper(archivePath, output) {
+		return output, false, err
+	}
+
+	tarOutput, tarErr := run7zzPipeline(ctx, nil, onStall, password,
+		unwrapTarArgs(archivePath),
+		append([]string{"l", "-slt", "-si", "-ttar"}, codePageArgs(codePage)...))
+	if tarErr != nil {
+		// 里面不是有效的 tar 时仍按单个文件显示
+		return output, false, nil
+	}
+	return tarOutput, true, nil
+}
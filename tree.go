@@ -0,0 +1,232 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ---------------------------------------------------------
+// 压缩包目录树相关代码
+// ---------------------------------------------------------
+
+// treeNode 是目录树中的一个节点, 既可以对应 7zz 列出的条目, 也可以是只在路径中出现过的隐含目录
+type treeNode struct {
+	item     archiveItem // item.name 为完整路径, 隐含目录只有 name 和 isDir
+	name     string      // 显示用的最后一级名称
+	parent   *treeNode
+	children []*treeNode
+	childIdx map[string]*treeNode
+	implied  bool      // 压缩包中没有该目录自己的条目
+	modTime  time.Time // 解析后的修改时间, 用于排序
+
+	// 目录节点为整个子树的合计值, 文件节点与 item 相同
+	totalSize   uint64
+	totalPacked uint64
+}
+
+// splitArchivePath 把 7zz 输出的路径拆分为各级名称, 同时兼容 / 和系统分隔符
+func splitArchivePath(p string) []string {
+	parts := strings.FieldsFunc(p, func(r rune) bool {
+		return r == '/' || r == os.PathSeparator
+	})
+	out := parts[:0]
+	for _, part := range parts {
+		if part == "." {
+			continue
+		}
+		out = append(out, part)
+	}
+	return out
+}
+
+// buildArchiveTree 根据扁平的条目列表构建目录树, 缺失的中间目录会被补齐
+func buildArchiveTree(items []archiveItem) *treeNode {
+	root := &treeNode{item: archiveItem{isDir: true}, implied: true}
+
+	for _, it := range items {
+		parts := splitArchivePath(it.name)
+		if len(parts) == 0 {
+			continue
+		}
+
+		node := root
+		for i, part := range parts {
+			child := node.childIdx[part]
+			if child == nil {
+				child = &treeNode{
+					item:    archiveItem{name: strings.Join(parts[:i+1], "/"), isDir: true},
+					name:    part,
+					parent:  node,
+					implied: true,
+				}
+				if node.childIdx == nil {
+					node.childIdx = make(map[string]*treeNode)
+				}
+				node.childIdx[part] = child
+				node.children = append(node.children, child)
+			}
+			node = child
+		}
+
+		// 同一路径出现多次时以最后一次为准, 隐含目录在这里变为真实条目
+		node.item = it
+		node.implied = false
+		node.modTime, _ = parseModified(it.modified)
+	}
+
+	root.sumSizes()
+	return root
+}
+
+func (n *treeNode) sumSizes() {
+	if !n.item.isDir {
+		n.totalSize = n.item.size
+		n.totalPacked = n.item.packed
+		return
+	}
+	n.totalSize = 0
+	n.totalPacked = 0
+	for _, c := range n.children {
+		c.sumSizes()
+		n.totalSize += c.totalSize
+		n.totalPacked += c.totalPacked
+	}
+}
+
+// pathNodes 返回从根到当前节点的路径, 包含根节点
+func (n *treeNode) pathNodes() []*treeNode {
+	var nodes []*treeNode
+	for cur := n; cur != nil; cur = cur.parent {
+		nodes = append(nodes, cur)
+	}
+	for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}
+	return nodes
+}
+
+// archiveBrowser 以"进入文件夹"的方式浏览目录树, 顶部显示面包屑导航
+type archiveBrowser struct {
+	items     []archiveItem // 7zz 列出的全部条目
+	unwrapTar bool          // 条目来自压缩流中的 tar, 解压时需要走管道
+	root      *treeNode
+	current   *treeNode
+	rows      []*treeNode // 当前目录下显示的行
+	rootName  string
+	filter    listFilter // 过滤条件, 进入其他目录时保持不变
+	sortCol   int        // 排序列, sortNone 表示保持 7zz 的顺序
+	sortDesc  bool
+
+	// 勾选状态, 勾选文件夹代表其整个子树
+	checked            map[*treeNode]bool
+	anchor             widget.ListItemID // Shift 多选的起点
+	onSelectionChanged func()
+
+	// 嵌套打开的外层压缩包, 以及当前压缩包所在的临时目录
+	levels       []archiveLevel
+	tempDir      string
+	onLeaveLevel func(i int)
+
+	list   *widget.List
+	crumbs *fyne.Container
+	bar    fyne.CanvasObject
+}
+
+func newArchiveBrowser() *archiveBrowser {
+	b := &archiveBrowser{checked: make(map[*treeNode]bool), anchor: -1}
+	b.crumbs = container.NewHBox()
+	b.bar = container.NewHScroll(b.crumbs)
+	return b
+}
+
+// setItems 用新的列表结果替换目录树, 并回到根目录
+func (b *archiveBrowser) setItems(rootName string, items []archiveItem) {
+	b.items = append(b.items[:0], items...)
+	b.rootName = rootName
+	b.root = buildArchiveTree(b.items)
+	b.clearChecked()
+	b.enter(b.root)
+	b.selectionChanged()
+}
+
+// clear 清空列表, 用于拖入新文件或重新读取列表前
+func (b *archiveBrowser) clear() {
+	b.items = b.items[:0]
+	b.root = nil
+	b.current = nil
+	b.rows = nil
+	b.clearChecked()
+	b.anchor = -1
+	b.refreshCrumbs()
+	b.selectionChanged()
+}
+
+func (b *archiveBrowser) enter(n *treeNode) {
+	if n == nil || !n.item.isDir {
+		return
+	}
+	b.current = n
+	b.refreshRows()
+	b.list.UnselectAll()
+	b.list.ScrollToTop()
+	b.list.Refresh()
+	b.refreshCrumbs()
+}
+
+func (b *archiveBrowser) refreshCrumbs() {
+	b.crumbs.RemoveAll()
+	// 嵌套打开时先显示外层压缩包, 点击返回该层
+	for i, lv := range b.levels {
+		if i > 0 {
+			b.crumbs.Add(widget.NewLabel("›"))
+		}
+		idx := i
+		btn := widget.NewButton(lv.name, func() {
+			if b.onLeaveLevel != nil {
+				b.onLeaveLevel(idx)
+			}
+		})
+		btn.Importance = widget.LowImportance
+		b.crumbs.Add(btn)
+	}
+	if b.current == nil {
+		// 内层压缩包正在读取列表
+		if len(b.levels) > 0 {
+			b.crumbs.Add(widget.NewLabel("›"))
+			btn := widget.NewButton(b.rootName, nil)
+			btn.Importance = widget.LowImportance
+			btn.Disable()
+			b.crumbs.Add(btn)
+		}
+		return
+	}
+	for i, n := range b.current.pathNodes() {
+		name := n.name
+		if n == b.root {
+			name = b.rootName
+		}
+		if i > 0 || len(b.levels) > 0 {
+			b.crumbs.Add(widget.NewLabel("›"))
+		}
+		target := n
+		btn := widget.NewButton(name, func() { b.enter(target) })
+		btn.Importance = widget.LowImportance
+		if n == b.current {
+			btn.Disable()
+		}
+		b.crumbs.Add(btn)
+	}
+}
+
+// row 返回列表第 id 行对应的节点
+func (b *archiveBrowser) row(id widget.ListItemID) *treeNode {
+	if id < 0 || id >= len(b.rows) {
+		return nil
+	}
+	return b.rows[id]
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ---------------------------------------------------------
+// 分卷压缩包相关代码
+// ---------------------------------------------------------
+
+// volNumericExts 是 .001 编号的分卷前必须带有的压缩包后缀, 避免把 log.001, backup.2024 这样的文件当作分卷
+const volNumericExts = `7z|zip|rar|tar|gz|tgz|bz2|tbz2?|xz|txz|zst|lz4|lzma|wim|iso|cab`
+
+var (
+	volNumericRe = regexp.MustCompile(`(?i)^(.+\.(?:` + volNumericExts + `))\.(\d{3,})$`) // demo.7z.001, demo.tar.gz.002
+	volPartRarRe = regexp.MustCompile(`(?i)^(.+)\.part(\d+)\.rar$`)                       // movie.part3.rar
+	volZipRe     = regexp.MustCompile(`(?i)^(.+)\.(z(\d{2,})|zip)$`)                      // demo.z01 ... demo.zip
+	volOldRarRe  = regexp.MustCompile(`(?i)^(.+)\.(r(\d{2,})|rar)$`)                      // demo.rar, demo.r00, demo.r01 ...
+)
+
+// volumeSet 描述一组分卷, 卷号从 1 开始
+type volumeSet struct {
+	base    string         // 卷集的基础名称, 用作解压目录名
+	first   string         // 交给 7zz 打开的卷
+	files   map[int]string // 已找到的卷, 卷号 -> 路径
+	max     int            // 已找到的最大卷号, 分卷 zip 为 .zip 所在的最后一卷
+	nameFor func(n int) string
+}
+
+// missing 返回 1 到 max 之间缺失的卷号
+func (v volumeSet) missing() []int {
+	var out []int
+	for i := 1; i <= v.max; i++ {
+		if _, ok := v.files[i]; !ok {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// missingText 返回缺失卷的说明, 没有缺失时返回空字符串
+func (v volumeSet) missingText() string {
+	missing := v.missing()
+	if len(missing) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(missing))
+	for _, n := range missing {
+		lines = append(lines, fmt.Sprintf("第 %d 卷: %s", n, v.nameFor(n)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// volumeCacheEntry 是 detectVolumeSet 的缓存结果, 所在目录修改过 (例如新增或删除了分卷) 时失效
+type volumeCacheEntry struct {
+	dirModTime time.Time
+	set        volumeSet
+	ok         bool
+}
+
+var (
+	volumeCacheMu sync.Mutex
+	volumeCache   = make(map[string]volumeCacheEntry)
+)
+
+// detectVolumeSet 判断 path 是否属于分卷压缩包, 并扫描同目录下的其它卷
+// 支持 .001 编号, .partN.rar, .zNN + .zip, .rar + .rNN 四种命名方式
+// 解压到预览等处会反复调用, 结果按压缩包路径缓存
+func detectVolumeSet(path string) (volumeSet, bool) {
+	info, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return volumeSet{}, false
+	}
+	volumeCacheMu.Lock()
+	cached, hit := volumeCache[path]
+	volumeCacheMu.Unlock()
+	if hit && cached.dirModTime.Equal(info.ModTime()) {
+		return cached.set, cached.ok
+	}
+
+	v, ok := scanVolumeSet(path)
+	volumeCacheMu.Lock()
+	volumeCache[path] = volumeCacheEntry{dirModTime: info.ModTime(), set: v, ok: ok}
+	volumeCacheMu.Unlock()
+	return v, ok
+}
+
+// scanVolumeSet 是不带缓存的 detectVolumeSet
+func scanVolumeSet(path string) (volumeSet, bool) {
+	dir := filepath.Dir(path)
+	name := filepath.Base(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return volumeSet{}, false
+	}
+	siblings := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			siblings[strings.ToLower(e.Name())] = e.Name()
+		}
+	}
+	lookup := func(n string) (string, bool) {
+		real, ok := siblings[strings.ToLower(n)]
+		if !ok {
+			return "", false
+		}
+		return filepath.Join(dir, real), true
+	}
+	// collect 从第 1 卷开始查找, known 为拖入的卷的卷号, 在它之前缺失的卷都会被报告
+	// 超过已找到的最大卷号后遇到第一个缺口即停止
+	collect := func(v *volumeSet, known int) {
+		v.files = make(map[int]string)
+		v.max = 0
+		for n := 1; n <= VOLUME_SCAN_MAX && (n <= known || n <= v.max+1); n++ {
+			if p, ok := lookup(v.nameFor(n)); ok {
+				v.files[n] = p
+				v.max = n
+			}
+		}
+	}
+	volNumber := func(digits string) int {
+		n, _ := strconv.Atoi(digits)
+		return n
+	}
+
+	if m := volPartRarRe.FindStringSubmatch(name); m != nil {
+		prefix, width := m[1], len(m[2])
+		v := volumeSet{base: prefix}
+		v.nameFor = func(n int) string { return fmt.Sprintf("%s.part%0*d.rar", prefix, width, n) }
+		collect(&v, volNumber(m[2]))
+		v.first = filepath.Join(dir, v.nameFor(1))
+		if p, ok := v.files[1]; ok {
+			v.first = p
+		}
+		return v, true
+	}
+
+	if m := volNumericRe.FindStringSubmatch(name); m != nil {
+		prefix, width := m[1], len(m[2])
+		v := volumeSet{base: stripArchiveSuffix(prefix)}
+		v.nameFor = func(n int) string { return fmt.Sprintf("%s.%0*d", prefix, width, n) }
+		collect(&v, volNumber(m[2]))
+		v.first = filepath.Join(dir, v.nameFor(1))
+		if p, ok := v.files[1]; ok {
+			v.first = p
+		}
+		return v, true
+	}
+
+	if m := volZipRe.FindStringSubmatch(name); m != nil {
+		prefix := m[1]
+		// 分卷 zip 的 .z01 ... .zNN 在前, .zip 为最后一卷, 7zz 需要从 .zip 打开
+		if _, ok := lookup(prefix + ".z01"); !ok && strings.EqualFold(m[2], "zip") {
+			return volumeSet{}, false
+		}
+		v := volumeSet{base: prefix}
+		zName := func(n int) string { return fmt.Sprintf("%s.z%02d", prefix, n) }
+		v.nameFor = zName
+		collect(&v, volNumber(m[3]))
+
+		v.max++
+		last := v.max
+		zipName := prefix + ".zip"
+		if p, ok := lookup(zipName); ok {
+			v.files[last] = p
+			zipName = filepath.Base(p)
+		}
+		v.nameFor = func(n int) string {
+			if n == last {
+				return zipName
+			}
+			return zName(n)
+		}
+		v.first = filepath.Join(dir, zipName)
+		return v, true
+	}
+
+	if m := volOldRarRe.FindStringSubmatch(name); m != nil {
+		prefix := m[1]
+		// 旧式 RAR 分卷: .rar 为第 1 卷, .r00 为第 2 卷, 依此类推
+		if _, ok := lookup(prefix + ".r00"); !ok && strings.EqualFold(m[2], "rar") {
+			return volumeSet{}, false
+		}
+		v := volumeSet{base: prefix}
+		v.nameFor = func(n int) string {
+			if n == 1 {
+				return prefix + ".rar"
+			}
+			return fmt.Sprintf("%s.r%02d", prefix, n-2)
+		}
+		known := 1
+		if m[3] != "" {
+			known = volNumber(m[3]) + 2
+		}
+		collect(&v, known)
+		v.first = filepath.Join(dir, v.nameFor(1))
+		if p, ok := v.files[1]; ok {
+			v.first = p
+		}
+		return v, true
+	}
+
+	return volumeSet{}, false
+}
+
+// firstVolume 返回分卷压缩包的入口卷, 普通文件原样返回
+func firstVolume(path string) string {
+	if v, ok := detectVolumeSet(path); ok {
+		return v.first
+	}
+	return path
+}
+
+// uniqueArchives 把同一组分卷合并为入口卷, 并去掉重复的路径
+func uniqueArchives(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = firstVolume(p)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		out = append(out, p)
+	}
+	return out
+}
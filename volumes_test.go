@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScanVolumeSet(t *testing.T) {
+	cases := []struct {
+		name    string
+		files   []string
+		drop    string
+		ok      bool
+		base    string
+		first   string
+		max     int
+		missing []int
+	}{
+		{"partN.rar 缺少中间的卷", []string{"movie.part1.rar", "movie.part2.rar", "movie.part4.rar"}, "movie.part4.rar", true, "movie", "movie.part1.rar", 4, []int{3}},
+		{"partN.rar 缺少第 1 卷, 保留卷号位数", []string{"movie.part02.rar", "movie.part03.rar"}, "movie.part03.rar", true, "movie", "movie.part01.rar", 3, []int{1}},
+		{"partN.rar 大小写不同", []string{"Movie.PART1.RAR", "movie.part2.rar"}, "movie.part2.rar", true, "movie", "Movie.PART1.RAR", 2, nil},
+		{".7z.001 完整", []string{"demo.7z.001", "demo.7z.002", "demo.7z.003"}, "demo.7z.002", true, "demo", "demo.7z.001", 3, nil},
+		{".7z.001 拖入最后一卷时报告之前的缺口", []string{"demo.7z.001", "demo.7z.002", "demo.7z.005"}, "demo.7z.005", true, "demo", "demo.7z.001", 5, []int{3, 4}},
+		{".7z.001 从第 1 卷起遇到缺口即停止", []string{"demo.7z.001", "demo.7z.003"}, "demo.7z.001", true, "demo", "demo.7z.001", 1, nil},
+		{".tar.gz.001", []string{"logs.tar.gz.001", "logs.tar.gz.002"}, "logs.tar.gz.001", true, "logs", "logs.tar.gz.001", 2, nil},
+		{"不带压缩包后缀的 .001 不是分卷", []string{"log.001", "log.002"}, "log.001", false, "", "", 0, nil},
+		{".z01 + .zip 完整", []string{"demo.z01", "demo.z02", "demo.zip"}, "demo.zip", true, "demo", "demo.zip", 3, nil},
+		{".z01 + .zip 缺少中间的卷", []string{"demo.z01", "demo.z03", "demo.zip"}, "demo.z03", true, "demo", "demo.zip", 4, []int{2}},
+		{".z01 + .zip 缺少最后的 .zip", []string{"demo.z01", "demo.z02"}, "demo.z02", true, "demo", "demo.zip", 3, []int{3}},
+		{"单独的 .zip 不是分卷", []string{"demo.zip"}, "demo.zip", false, "", "", 0, nil},
+		{".rar + .r00", []string{"old.rar", "old.r00", "old.r01"}, "old.r01", true, "old", "old.rar", 3, nil},
+		{".rar + .r00 缺少 .rar", []string{"old.r00", "old.r01"}, "old.r00", true, "old", "old.rar", 3, []int{1}},
+		{"单独的 .rar 不是分卷", []string{"solo.rar"}, "solo.rar", false, "", "", 0, nil},
+	}
+	for _, c := range cases {
+		dir := t.TempDir()
+		for _, f := range c.files {
+			if err := os.WriteFile(filepath.Join(dir, f), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		v, ok := scanVolumeSet(filepath.Join(dir, c.drop))
+		if ok != c.ok {
+			t.Errorf("%s: ok = %v, want %v", c.name, ok, c.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if v.base != c.base || v.first != filepath.Join(dir, c.first) || v.max != c.max {
+			t.Errorf("%s: base = %q, first = %q, max = %d, want %q, %q, %d", c.name, v.base, filepath.Base(v.first), v.max, c.base, c.first, c.max)
+		}
+		if got := v.missing(); !reflect.DeepEqual(got, c.missing) {
+			t.Errorf("%s: missing = %v, want %v", c.name, got, c.missing)
+		}
+	}
+}
+
+// TestDetectVolumeSetRescansChangedDir 目录内容变化后不能沿用缓存中缺卷的结果
+func TestDetectVolumeSetRescansChangedDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"set.7z.001", "set.7z.003"} {
+		if err := os.WriteFile(filepath.Join(dir, f), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	drop := filepath.Join(dir, "set.7z.003")
+	v, ok := detectVolumeSet(drop)
+	if !ok || !reflect.DeepEqual(v.missing(), []int{2}) {
+		t.Fatalf("detectVolumeSet = %v, missing %v, want 缺少第 2 卷", ok, v.missing())
+	}
+	if got := v.missingText(); got != "第 2 卷: set.7z.002" {
+		t.Errorf("missingText = %q", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "set.7z.002"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 文件系统的时间精度可能很粗, 显式推后目录的修改时间
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(dir, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := detectVolumeSet(drop); len(v.missing()) != 0 || v.missingText() != "" {
+		t.Errorf("补齐分卷后 missing = %v, want 无", v.missing())
+	}
+}